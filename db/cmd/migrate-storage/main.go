@@ -0,0 +1,151 @@
+// migrate-storage: pindahin PDF scan antar backend storage (local <-> s3) dan
+// update kolom scan_records.path. Setting backend & database dari env / .env
+// yang sama dengan service db. Aman dijalanin ulang, file yang udah ada di
+// tujuan dilewati.
+//
+//	go run ./cmd/migrate-storage -from local -to s3 -dry-run
+//	go run ./cmd/migrate-storage -from local -to s3
+//	go run ./cmd/migrate-storage -from s3 -to local -delete
+//
+// Setelah selesai, ganti STORAGE_BACKEND service db ke backend tujuan lalu restart.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"scanner-bridge/database"
+	"scanner-bridge/storage"
+)
+
+func main() {
+	from := flag.String("from", "local", "Backend asal (local / s3)")
+	to := flag.String("to", "", "Backend tujuan (local / s3, wajib)")
+	del := flag.Bool("delete", false, "Hapus file di backend asal setelah berhasil disalin")
+	dryRun := flag.Bool("dry-run", false, "Cuma tampilkan yang bakal dipindah")
+	flag.Parse()
+	if *to == "" || *to == *from {
+		fmt.Fprintln(os.Stderr, "Usage: migrate-storage -from local -to s3 [-delete] [-dry-run]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	database.InitDB()
+	src, err := storage.Open(*from)
+	if err != nil {
+		log.Fatal("Gagal buka storage asal:", err)
+	}
+	dst, err := storage.Open(*to)
+	if err != nil {
+		log.Fatal("Gagal buka storage tujuan:", err)
+	}
+	ctx := context.Background()
+
+	keys, err := src.List(ctx, "")
+	if err != nil {
+		log.Fatal("Gagal list file di storage asal:", err)
+	}
+
+	// Record dicocokin lewat key (nama file), path lama bisa relatif / beda folder
+	var records []database.ScanRecord
+	if err := database.DB.Select("id", "path").Find(&records).Error; err != nil {
+		log.Fatal("Gagal ambil scan_records:", err)
+	}
+	byKey := make(map[string][]uint)
+	for _, rec := range records {
+		key := storage.KeyOf(rec.Path)
+		byKey[key] = append(byKey[key], rec.ID)
+	}
+
+	fmt.Printf("%d file di %s, %d record\n", len(keys), src.Name(), len(records))
+	copied, skipped, failed, updated := 0, 0, 0, 0
+	for _, key := range keys {
+		exists, err := dst.Exists(ctx, key)
+		if err != nil {
+			log.Printf("GAGAL %s: cek tujuan: %v\n", key, err)
+			failed++
+			continue
+		}
+		if *dryRun {
+			status := "salin"
+			if exists {
+				status = "sudah ada di tujuan"
+			}
+			fmt.Printf("%s: %s, %d record\n", key, status, len(byKey[key]))
+			continue
+		}
+
+		if exists {
+			skipped++
+		} else {
+			if err := copyObject(ctx, src, dst, key); err != nil {
+				log.Printf("GAGAL %s: %v\n", key, err)
+				failed++
+				continue
+			}
+			copied++
+		}
+
+		if ids := byKey[key]; len(ids) > 0 {
+			res := database.DB.Model(&database.ScanRecord{}).Where("id IN ?", ids).Update("path", dst.Location(key))
+			if res.Error != nil {
+				// File asal jangan dihapus kalau record-nya masih nunjuk ke sana
+				log.Printf("GAGAL %s: update path: %v\n", key, res.Error)
+				failed++
+				continue
+			}
+			updated += int(res.RowsAffected)
+		}
+		if *del {
+			if err := src.Delete(ctx, key); err != nil {
+				log.Printf("WARNING %s: gagal hapus dari asal: %v\n", key, err)
+			}
+		}
+	}
+
+	listed := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		listed[key] = true
+	}
+	missing := 0
+	for key := range byKey {
+		if !listed[key] {
+			missing++
+		}
+	}
+
+	if *dryRun {
+		fmt.Println("Dry run, gak ada yang diubah")
+	} else {
+		fmt.Printf("Selesai: %d disalin, %d sudah ada, %d gagal, %d record diupdate\n", copied, skipped, failed, updated)
+	}
+	if missing > 0 {
+		fmt.Printf("WARNING: %d file yang dirujuk scan_records gak ada di %s\n", missing, src.Name())
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func copyObject(ctx context.Context, src, dst storage.Storage, key string) error {
+	obj, err := src.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+	if err := dst.Put(ctx, key, obj, obj.Size); err != nil {
+		return err
+	}
+	// Pastiin beneran sampai sebelum record dipindah
+	check, err := dst.Open(ctx, key)
+	if err != nil {
+		return fmt.Errorf("verifikasi tujuan: %v", err)
+	}
+	check.Close()
+	if check.Size != obj.Size {
+		return fmt.Errorf("ukuran di tujuan %d byte, asal %d byte", check.Size, obj.Size)
+	}
+	return nil
+}
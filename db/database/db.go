@@ -1,9 +1,12 @@
 package database
 
 import (
+	"database/sql/driver"
+	"dbclient"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -14,11 +17,63 @@ import (
 
 var DB *gorm.DB
 
+// ScanRecord: satu versi PDF dokumen. Dokumen = NPSN + DocKey (SN BAPP),
+// scan ulang bikin versi baru dan cuma satu versi yang IsCurrent.
 type ScanRecord struct {
-	ID        uint      `gorm:"primaryKey"`
-	NPSN      string    `json:"npsn" gorm:"type:varchar(50);index"` // Update type agar bisa di-index/FK
-	Path      string    `json:"path"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        uint   `gorm:"primaryKey"`
+	NPSN      string `json:"npsn" gorm:"type:varchar(50);index;index:idx_scan_records_doc,priority:1"` // Update type agar bisa di-index/FK
+	DocKey    string `json:"doc_key" gorm:"type:varchar(100);index:idx_scan_records_doc,priority:2"`   // Bukan sn_bapp, kolom itu di-drop di bawah
+	Version   int    `json:"version" gorm:"not null;default:1"`
+	IsCurrent bool   `json:"is_current" gorm:"not null;default:true;index"`
+	Path      string `json:"path"`
+	// Diisi selama PDF masih di folder staging (simpan belum selesai), lihat recoverStaging
+	StagingPath string `json:"-" gorm:"type:varchar(255);not null;default:''"`
+	// Instance service db yang nulis file staging-nya, cuma instance itu yang boleh recover
+	StagingHost string `json:"-" gorm:"type:varchar(100);not null;default:''"`
+	// Hash & metadata tiap halaman PDF, kosong buat record sebelum ada provenance
+	Pages PageList `json:"pages" gorm:"type:mediumtext"`
+	// SaveRequest.SubmissionID, NULL buat simpan tanpa ID (unique index
+	// MySQL ngebolehin banyak NULL)
+	SubmissionID *string   `json:"-" gorm:"type:varchar(128);uniqueIndex"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type PageProvenance = dbclient.PageProvenance
+
+type PageList []PageProvenance
+
+func (l PageList) Value() (driver.Value, error) { return jsonValue(l) }
+func (l *PageList) Scan(src interface{}) error  { return scanJSON(src, l) }
+
+// legacyDocKey: record sebelum ada versi belum punya doc_key, ambil dari
+// nama file NPSN_SNBAPP.pdf
+func legacyDocKey(rec ScanRecord) string {
+	name := strings.TrimSuffix(filepath.Base(rec.Path), ".pdf")
+	return strings.TrimPrefix(name, rec.NPSN+"_")
+}
+
+// docVersionIndex: unique (npsn, doc_key, version), simpan barengan yang
+// dapat nomor versi sama ditolak di sini kalau lolos dari lock transaksi
+const docVersionIndex = "idx_scan_records_doc_version"
+
+// logDuplicateVersions: daftar versi dokumen dobel yang bikin unique index
+// gak bisa dibuat, biar gampang diberesin manual
+func logDuplicateVersions() {
+	var dups []struct {
+		NPSN    string
+		DocKey  string
+		Version int
+		Total   int
+	}
+	err := DB.Raw(`SELECT npsn, doc_key, version, COUNT(*) AS total FROM scan_records
+		GROUP BY npsn, doc_key, version HAVING COUNT(*) > 1`).Scan(&dups).Error
+	if err != nil {
+		log.Println("WARNING: Gagal cek versi dokumen dobel:", err)
+		return
+	}
+	for _, d := range dups {
+		log.Printf("WARNING: Dokumen %s/%s versi %d ada %d record\n", d.NPSN, d.DocKey, d.Version, d.Total)
+	}
 }
 
 func InitDB() {
@@ -55,7 +110,7 @@ func InitDB() {
 	}
 
 	// 1. Auto Migrate (Update Kolom yang ada, Create Table)
-	err = DB.AutoMigrate(&ScanRecord{})
+	err = DB.AutoMigrate(&ScanRecord{}, &Station{}, &ProfileConfig{})
 	if err != nil {
 		log.Fatal("Gagal migrasi tabel:", err)
 	}
@@ -74,7 +129,41 @@ func InitDB() {
 		}
 	}
 
-	// 3. Tambah Foreign Key ke tabel schools (npsn)
+	// 3. Isi doc_key record lama biar kebaca sebagai versi 1 dokumennya
+	var legacy []ScanRecord
+	if err := DB.Where("doc_key = '' OR doc_key IS NULL").Find(&legacy).Error; err != nil {
+		log.Println("WARNING: Gagal cek record lama tanpa doc_key:", err)
+	}
+	filled := 0
+	for _, rec := range legacy {
+		key := legacyDocKey(rec)
+		if key == "" {
+			continue
+		}
+		if err := DB.Model(&ScanRecord{}).Where("id = ?", rec.ID).Update("doc_key", key).Error; err != nil {
+			log.Printf("WARNING: Gagal isi doc_key record %d: %v\n", rec.ID, err)
+			continue
+		}
+		filled++
+	}
+	if filled > 0 {
+		fmt.Printf("doc_key %d record lama berhasil diisi dari nama file\n", filled)
+	}
+
+	// 3b. Satu nomor versi cuma boleh sekali per dokumen. Dibikin manual
+	// setelah doc_key lama keisi, kalau lewat tag AutoMigrate record lama
+	// (doc_key masih kosong, versi 1 semua) bakal dianggap dobel.
+	if !migrator.HasIndex(&ScanRecord{}, docVersionIndex) {
+		err := DB.Exec(fmt.Sprintf("CREATE UNIQUE INDEX %s ON scan_records(npsn, doc_key, version)", docVersionIndex)).Error
+		if err != nil {
+			log.Printf("WARNING: Gagal bikin unique index %s, cek versi dokumen yang dobel: %v\n", docVersionIndex, err)
+			logDuplicateVersions()
+		} else {
+			fmt.Printf("Unique index %s berhasil dibuat\n", docVersionIndex)
+		}
+	}
+
+	// 4. Tambah Foreign Key ke tabel schools (npsn)
 	// Pastikan tabel schools ada dan npsn tipe datanya cocok (biasanya varchar)
 	// Kita pakai Raw SQL biar aman kalau constraint belum ada
 
@@ -96,7 +185,7 @@ func InitDB() {
 		}
 	}
 
-	// 4. Tambah Index pada schools(termin) untuk optimasi query stats
+	// 5. Tambah Index pada schools(termin) untuk optimasi query stats
 	// Cek apakah index sudah ada dengan query manual ke information_schema atau coba create & ignore error
 	// Kita coba Create Index dan handle error jika sudah ada
 	indexName := "idx_schools_termin"
@@ -0,0 +1,12 @@
+package database
+
+import "time"
+
+// ProfileConfig: satu versi definisi profile scan + aturan post-processing
+// yang ditarik semua bridge. Gak pernah diupdate, publish = insert versi baru.
+type ProfileConfig struct {
+	Version    uint      `json:"version" gorm:"primaryKey;autoIncrement"`
+	Definition string    `json:"-" gorm:"type:longtext"` // JSON profiles + quality
+	Note       string    `json:"note" gorm:"type:varchar(255)"`
+	CreatedAt  time.Time `json:"created_at"`
+}
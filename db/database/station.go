@@ -0,0 +1,71 @@
+package database
+
+import (
+	"database/sql/driver"
+	"dbclient"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Station: satu PC scanner-bridge, diupdate tiap heartbeat
+type Station struct {
+	ID              string     `json:"id" gorm:"primaryKey;type:varchar(64)"`
+	Name            string     `json:"name" gorm:"type:varchar(100)"`
+	Hostname        string     `json:"hostname" gorm:"type:varchar(100)"`
+	Version         string     `json:"version" gorm:"type:varchar(50)"`
+	OS              string     `json:"os" gorm:"type:varchar(50)"`
+	Backends        JSONList   `json:"backends" gorm:"type:text"`
+	Devices         DeviceList `json:"devices" gorm:"type:text"`
+	Profiles        JSONList   `json:"profiles" gorm:"type:text"`
+	ConfigVersion   int        `json:"config_version"` // Versi ProfileConfig yang dipakai bridge
+	LastError       string     `json:"last_error" gorm:"type:text"`
+	LastErrorAt     *time.Time `json:"last_error_at"`
+	IntervalSeconds int        `json:"interval_seconds"`
+	RemoteAddr      string     `json:"remote_addr" gorm:"type:varchar(100)"`
+	LastSeen        time.Time  `json:"last_seen" gorm:"index"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// Device sama dengan yang dikirim bridge di heartbeat
+type Device = dbclient.Device
+
+// Kolom list disimpen sebagai JSON di kolom text, biar gak perlu tabel anak
+
+type JSONList []string
+
+func (l JSONList) Value() (driver.Value, error) { return jsonValue(l) }
+func (l *JSONList) Scan(src interface{}) error  { return scanJSON(src, l) }
+
+type DeviceList []Device
+
+func (d DeviceList) Value() (driver.Value, error) { return jsonValue(d) }
+func (d *DeviceList) Scan(src interface{}) error  { return scanJSON(src, d) }
+
+func jsonValue(v interface{}) (driver.Value, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func scanJSON(src interface{}, dst interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		if len(v) == 0 {
+			return nil
+		}
+		return json.Unmarshal(v, dst)
+	case string:
+		if v == "" {
+			return nil
+		}
+		return json.Unmarshal([]byte(v), dst)
+	default:
+		return fmt.Errorf("tipe kolom JSON tidak didukung: %T", src)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"dbclient"
+	"fmt"
+	"scanner-bridge/database"
+)
+
+// NPSN & SN BAPP dari request dicek dulu sebelum nyentuh filesystem / DB.
+// Nama file PDF cuma boleh dibangun dari docIdentity, bukan string request.
+
+type docIdentity struct {
+	NPSN   dbclient.NPSN
+	SNBapp dbclient.SNBapp
+}
+
+// validateIdentity: format NPSN & SN BAPP + NPSN harus ada di schools.
+// err cuma buat gagal query DB.
+func validateIdentity(npsnStr, snStr string) (docIdentity, []dbclient.PartError, error) {
+	var id docIdentity
+	var errs []dbclient.PartError
+
+	npsn, err := dbclient.ParseNPSN(npsnStr)
+	if err != nil {
+		errs = append(errs, dbclient.PartError{Part: "npsn", Message: err.Error()})
+	} else {
+		ok, err := schoolExists(npsn)
+		if err != nil {
+			return id, nil, err
+		}
+		if !ok {
+			errs = append(errs, dbclient.PartError{Part: "npsn", Message: fmt.Sprintf("NPSN %s tidak terdaftar di data sekolah", npsn)})
+		}
+		id.NPSN = npsn
+	}
+
+	sn, err := dbclient.ParseSNBapp(snStr)
+	if err != nil {
+		errs = append(errs, dbclient.PartError{Part: "sn_bapp", Message: err.Error()})
+	}
+	id.SNBapp = sn
+	return id, errs, nil
+}
+
+func schoolExists(npsn dbclient.NPSN) (bool, error) {
+	var n int64
+	if err := database.DB.Table("schools").Where("npsn = ?", string(npsn)).Count(&n).Error; err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// docFileBase: NPSN_SNBAPP, versi 2 ke atas NPSN_SNBAPP_vN (tanpa .pdf)
+func docFileBase(id docIdentity, version int) string {
+	base := fmt.Sprintf("%s_%s", id.NPSN, id.SNBapp)
+	if version > 1 {
+		base += fmt.Sprintf("_v%d", version)
+	}
+	return base
+}
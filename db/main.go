@@ -1,42 +1,36 @@
 package main
 
 import (
-	"encoding/base64"
+	"bytes"
+	"context"
+	"dbclient"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"path/filepath"
 	"scanner-bridge/database"
+	"scanner-bridge/storage"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/jung-kurt/gofpdf"
 	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ScanPair struct {
 	Front string `json:"front"`          // Base64 string
 	Back  string `json:"back,omitempty"` // Base64 string
 }
-type DeleteRequest struct {
-	NPSN string `json:"npsn"`
-}
 type Response struct {
 	Success bool       `json:"success"`
 	Data    []ScanPair `json:"data,omitempty"`
 	Message string     `json:"message,omitempty"`
 }
-type SaveRequest struct {
-	DocName    string `json:"doc_name"`
-	NPSN       string `json:"npsn"`
-	SNBapp     string `json:"sn_bapp"`
-	HasilCek   string `json:"hasil_cek"`
-	Kode       string `json:"kode"` // Added field
-	ImageFront string `json:"image_front"`
-	ImageBack  string `json:"image_back"`
-}
 
 // Middleware manual buat CORS (biar Next.js bisa akses)
 func enableCors(w *http.ResponseWriter) {
@@ -56,37 +50,145 @@ func home(w http.ResponseWriter, r *http.Request) {
 		"time":    time.Now(),
 	})
 }
+
+// Simpan makan memori gede (body sampai 200 MB + decode gambar + PDF), jadi
+// yang jalan barengan dibatasin. Ubah lewat SAVE_CONCURRENCY.
+const (
+	defaultSaveConcurrency = 2
+	saveQueueWait          = 30 * time.Second
+)
+
+var saveSlots = make(chan struct{}, defaultSaveConcurrency)
+
+func initSaveSlots() {
+	n, err := strconv.Atoi(os.Getenv("SAVE_CONCURRENCY"))
+	if err != nil || n < 1 {
+		return
+	}
+	saveSlots = make(chan struct{}, n)
+}
+
+// acquireSave nunggu giliran simpan, kalau kelamaan balikin 503 biar client
+// (outbox bridge) coba lagi nanti. ok = false berarti response udah ditulis.
+func acquireSave(w http.ResponseWriter, r *http.Request) (release func(), ok bool) {
+	timer := time.NewTimer(saveQueueWait)
+	defer timer.Stop()
+	select {
+	case saveSlots <- struct{}{}:
+		return func() { <-saveSlots }, true
+	case <-r.Context().Done():
+		return nil, false
+	case <-timer.C:
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Server lagi sibuk nyimpen dokumen lain, coba lagi sebentar lagi"})
+		return nil, false
+	}
+}
+
 func saveHandler(w http.ResponseWriter, r *http.Request) {
 	enableCors(&w)
 	if r.Method == "OPTIONS" {
 		return
 	}
+	release, ok := acquireSave(w, r)
+	if !ok {
+		return
+	}
+	defer release()
 
-	var req SaveRequest
+	// Base64 ~4/3 ukuran asli
+	r.Body = http.MaxBytesReader(w, r.Body, maxSaveBytes/3*4)
+	var req dbclient.SaveRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": fmt.Sprintf("Request lebih dari %d MB", maxSaveBytes>>20)})
+			return
+		}
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Request tidak valid"})
 		return
 	}
 
-	// --- CEK DUPLIKAT DULU DI SINI ---
-	// (Check Logika Duplikat dihapus karena kolom sn_bapp sudah didrop)
-	// Jika ingin cek duplikat, bisa pakai NPSN saja atau logika lain.
-	// Untuk sekarang, kita allow multiple scan per NPSN atau client yang handle.
-	// --------------------------------
-	// --------------------------------
+	id, errs, err := validateIdentity(req.NPSN, req.SNBapp)
+	if err != nil {
+		log.Println("Error cek data sekolah:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Gagal cek data sekolah"})
+		return
+	}
+	pages, pageErrs := decodeJSONPages(req)
+	errs = append(errs, pageErrs...)
+	if len(errs) > 0 {
+		writePartErrors(w, errs)
+		return
+	}
+	saveDocument(r.Context(), w, req, id, pages)
+}
 
-	storageDir := os.Getenv("SCAN_STORAGE_PATH")
-	if storageDir == "" {
-		storageDir = "./scans" // Default Linux friendly
+// saveDocument: bagian simpan yang sama buat POST /save (JSON) & /save/upload
+// (multipart), identifier & halaman udah lolos validasi. NPSN / SN BAPP
+// dipakai dari id, bukan dari req.
+func saveDocument(ctx context.Context, w http.ResponseWriter, req dbclient.SaveRequest, id docIdentity, pages []pageImage) {
+	if req.Mode != dbclient.SaveModeNew && req.Mode != dbclient.SaveModeNewVersion {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Mode simpan tidak dikenal: " + req.Mode})
+		return
+	}
+	if len(req.SubmissionID) > maxSubmissionID {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": fmt.Sprintf("submission_id lebih dari %d karakter", maxSubmissionID)})
+		return
+	}
+	// Kiriman ulang (mis. retry outbox setelah timeout) gak boleh bikin record dobel
+	if req.SubmissionID != "" && replaySubmission(w, req.SubmissionID, id) {
+		return
 	}
-	os.MkdirAll(storageDir, 0755)
 
-	fileNameBase := fmt.Sprintf("%s_%s", req.NPSN, req.SNBapp)
-	pdfPath := filepath.Join(storageDir, fileNameBase+".pdf")
+	// --- CEK DUPLIKAT DULU DI SINI ---
+	// Dokumen = NPSN + SN BAPP. Scan ulang ditolak kecuali mode new_version,
+	// versi lama tetap disimpan buat history.
+	version := 1
+	var latest database.ScanRecord
+	err := database.DB.Where("npsn = ? AND doc_key = ?", string(id.NPSN), string(id.SNBapp)).Order("version DESC").First(&latest).Error
+	switch {
+	case err == nil && req.Mode != dbclient.SaveModeNewVersion:
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("Dokumen ini sudah tersimpan (versi %d). Simpan sebagai versi baru kalau mau ganti hasil scan.", latest.Version),
+		})
+		return
+	case err == nil:
+		version = latest.Version + 1
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		log.Println("Error cek versi dokumen:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Gagal cek data dokumen"})
+		return
+	}
+
+	// Versi 1 tetap NPSN_SNBAPP.pdf biar sama dengan file lama
+	fileNameBase := docFileBase(id, version)
+	key := fileNameBase + ".pdf"
+	if err := storage.CheckKey(key); err != nil {
+		log.Println("Nama file PDF ditolak:", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "NPSN / SN BAPP tidak bisa dipakai sebagai nama file"})
+		return
+	}
 
 	// Cek juga secara fisik apakah filenya ada (opsional tapi bagus buat jaga-jaga)
-	if _, err := os.Stat(pdfPath); err == nil {
+	exists, err := store.Exists(ctx, key)
+	if err != nil {
+		log.Println("Error cek storage:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Gagal cek storage"})
+		return
+	}
+	if exists {
 		w.WriteHeader(http.StatusConflict)
 		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "File PDF sudah ada di storage!"})
 		return
@@ -94,53 +196,78 @@ func saveHandler(w http.ResponseWriter, r *http.Request) {
 
 	pdf := gofpdf.New("P", "mm", "A4", "")
 
-	processImg := func(b64Str, label string) string {
-		if b64Str == "" {
-			return ""
-		}
-		i := strings.Index(b64Str, ",")
-		if i != -1 {
-			b64Str = b64Str[i+1:]
-		}
-		data, _ := base64.StdEncoding.DecodeString(b64Str)
-
-		tmpPath := filepath.Join(os.TempDir(), fmt.Sprintf("tmp_%s_%s.jpg", fileNameBase, label))
-		os.WriteFile(tmpPath, data, 0644)
+	// Gambar langsung dari memory, gak lewat file temp
+	for i, page := range pages {
+		imgName := fmt.Sprintf("page_%d", i+1)
+		opts := gofpdf.ImageOptions{ImageType: page.Type}
+		pdf.RegisterImageOptionsReader(imgName, opts, bytes.NewReader(page.Data))
 
 		pdf.AddPage()
 		pdf.SetFont("Arial", "B", 12)
-		pdf.Cell(0, 10, "Halaman "+label)
-		pdf.ImageOptions(tmpPath, 10, 20, 190, 0, false, gofpdf.ImageOptions{ImageType: "JPG"}, 0, "")
-
-		return tmpPath
+		pdf.Cell(0, 10, pageTitle(i, page.Page))
+		pdf.ImageOptions(imgName, 10, 20, 190, 0, false, opts, 0, "")
 	}
 
-	tmpF := processImg(req.ImageFront, "Depan")
-	tmpB := processImg(req.ImageBack, "Belakang")
-
-	if err := pdf.OutputFileAndClose(pdfPath); err != nil {
+	stagingPath, err := writeStaging(pdf, fileNameBase)
+	if err != nil {
+		log.Println("Gagal bikin PDF:", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Gagal membuat PDF"})
 		return
 	}
 
-	if tmpF != "" {
-		os.Remove(tmpF)
-	}
-	if tmpB != "" {
-		os.Remove(tmpB)
-	}
-
 	newRecord := database.ScanRecord{
-		NPSN: req.NPSN,
-		Path: pdfPath,
-	}
-
-	result := database.DB.Create(&newRecord)
-	if result.Error != nil {
-		os.Remove(pdfPath)
+		NPSN:      string(id.NPSN),
+		DocKey:    string(id.SNBapp),
+		Version:   version,
+		IsCurrent: true,
+		Path:      store.Location(key),
+		// Belum di tempatnya sampai finishStaging
+		StagingPath: stagingPath,
+		StagingHost: stagingHost(),
+		Pages:       pageProvenance(pages),
+	}
+	if req.SubmissionID != "" {
+		newRecord.SubmissionID = &req.SubmissionID
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// Kunci baris dokumen ini biar dua simpan barengan gak dapat nomor versi sama
+		var maxVersion int
+		if err := tx.Model(&database.ScanRecord{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("npsn = ? AND doc_key = ?", string(id.NPSN), string(id.SNBapp)).
+			Select("COALESCE(MAX(version), 0)").Scan(&maxVersion).Error; err != nil {
+			return err
+		}
+		if maxVersion >= version {
+			return errVersionTaken
+		}
+		if err := tx.Create(&newRecord).Error; err != nil {
+			return err
+		}
+		// Versi lama tetap current sampai PDF baru beneran sampai storage,
+		// finishStaging yang mindahin tanda current-nya (default kolom true,
+		// jadi gak bisa langsung di-insert false)
+		newRecord.IsCurrent = false
+		return tx.Model(&newRecord).Update("is_current", false).Error
+	})
+	if err != nil {
+		os.Remove(stagingPath)
 
-		fmt.Println("Gagal simpan (Duplikat?):", result.Error)
+		// Kiriman yang sama barengan, yang kalah kena unique index submission_id
+		// (atau keduluan nomor versinya)
+		if req.SubmissionID != "" && replaySubmission(w, req.SubmissionID, id) {
+			return
+		}
+		if errors.Is(err, errVersionTaken) || isVersionConflict(err) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": false,
+				"message": "Dokumen ini barusan disimpan dari tempat lain, cek history lalu coba lagi.",
+			})
+			return
+		}
+		fmt.Println("Gagal simpan (Duplikat?):", err)
 		w.WriteHeader(http.StatusConflict) // 409 Conflict
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": false,
@@ -149,18 +276,70 @@ func saveHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := finishStaging(ctx, &newRecord); err != nil {
+		log.Printf("Gagal mindahin PDF %s ke storage %s: %v\n", key, store.Name(), err)
+		if dErr := discardRecord(newRecord); dErr != nil {
+			// Row tetap ada, recoverStaging yang beresin waktu start berikutnya
+			log.Printf("Gagal batalin record %d: %v\n", newRecord.ID, dErr)
+		} else {
+			os.Remove(stagingPath)
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Gagal menyimpan PDF ke storage"})
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
-		"message": "Dokumen berhasil digabung jadi PDF dan disimpan!",
+		"id":      newRecord.ID,
+		"version": version,
+		"pages":   len(pages),
+		"message": fmt.Sprintf("Dokumen %d halaman berhasil digabung jadi PDF dan disimpan (versi %d)!", len(pages), version),
 	})
 }
 
-type DashboardStat struct {
-	Termin       string `json:"termin"`
-	TotalSchools int64  `json:"total_schools"`
-	Scanned      int64  `json:"scanned"`
-	LogsAccepted int64  `json:"logs_accepted"`
+const maxSubmissionID = 128
+
+// replaySubmission balikin hasil simpan sebelumnya kalau submission id udah
+// pernah dipakai buat dokumen yang sama. false = belum ada, lanjut simpan baru.
+func replaySubmission(w http.ResponseWriter, submissionID string, id docIdentity) bool {
+	var rec database.ScanRecord
+	err := database.DB.Where("submission_id = ?", submissionID).First(&rec).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false
+	}
+	if err != nil {
+		log.Println("Error cek submission_id:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Gagal cek data dokumen"})
+		return true
+	}
+	if rec.NPSN != string(id.NPSN) || rec.DocKey != string(id.SNBapp) {
+		// ID sama tapi dokumennya lain, jangan sampai dianggap udah kesimpen
+		log.Printf("Submission %s udah dipakai record %d (%s/%s), ditolak buat %s/%s\n", submissionID, rec.ID, rec.NPSN, rec.DocKey, id.NPSN, id.SNBapp)
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "submission_id sudah dipakai dokumen lain, kirim ulang dengan ID baru"})
+		return true
+	}
+	if rec.StagingPath != "" {
+		// Simpan pertama belum selesai, 503 biar client coba lagi nanti
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Kiriman ini masih diproses, coba lagi sebentar lagi"})
+		return true
+	}
+
+	log.Printf("Submission %s udah tersimpan jadi record %d, kiriman ulang diabaikan\n", submissionID, rec.ID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"id":       rec.ID,
+		"version":  rec.Version,
+		"pages":    len(rec.Pages),
+		"replayed": true,
+		"message":  fmt.Sprintf("Dokumen ini sudah tersimpan sebelumnya (versi %d), kiriman ulang diabaikan", rec.Version),
+	})
+	return true
 }
 
 func statsHandler(w http.ResponseWriter, r *http.Request) {
@@ -176,7 +355,7 @@ func statsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var totalRes, scannedRes, logsRes []Res
-	statsMap := make(map[string]*DashboardStat)
+	statsMap := make(map[string]*dbclient.DashboardStat)
 
 	// 1. Get Total Schools per Termin
 	// SELECT termin, COUNT(*) FROM schools GROUP BY termin
@@ -184,7 +363,7 @@ func statsHandler(w http.ResponseWriter, r *http.Request) {
 
 	// 2. Get Scanned Count per Termin
 	database.DB.Table("schools").
-		Joins("INNER JOIN scan_records ON scan_records.npsn = schools.npsn").
+		Joins("INNER JOIN scan_records ON scan_records.npsn = schools.npsn AND scan_records.staging_path = ''").
 		Select("schools.termin, count(distinct scan_records.npsn) as cnt").
 		Group("schools.termin").
 		Scan(&scannedRes)
@@ -198,9 +377,9 @@ func statsHandler(w http.ResponseWriter, r *http.Request) {
 		Scan(&logsRes)
 
 	// Merge Results
-	getStat := func(termin string) *DashboardStat {
+	getStat := func(termin string) *dbclient.DashboardStat {
 		if _, ok := statsMap[termin]; !ok {
-			statsMap[termin] = &DashboardStat{Termin: termin}
+			statsMap[termin] = &dbclient.DashboardStat{Termin: termin}
 		}
 		return statsMap[termin]
 	}
@@ -216,7 +395,7 @@ func statsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Convert map to slice (Random order, sorted in frontend)
-	var finalStats []DashboardStat
+	var finalStats []dbclient.DashboardStat
 	for _, s := range statsMap {
 		if s.Termin != "" {
 			finalStats = append(finalStats, *s)
@@ -230,30 +409,11 @@ func statsHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-type RecordResponse struct {
-	ID          uint      `json:"id"`
-	NPSN        string    `json:"npsn"`
-	NamaSekolah string    `json:"nama_sekolah"`
-	SNBapp      string    `json:"sn_bapp"`
-	HasilCek    string    `json:"hasil_cek"`
-	Kode        string    `json:"kode"`
-	Path        string    `json:"path"`
-	CreatedAt   time.Time `json:"created_at"`
-}
-
-func recordsHandler(w http.ResponseWriter, r *http.Request) {
-	enableCors(&w)
-	if r.Method == "OPTIONS" {
-		return
-	}
-
-	searchNPSN := r.URL.Query().Get("npsn")
-	var records []RecordResponse
-
-	// Query kompleks untuk join scan_records, schools, dan log terakhir
-	query := `
+// Query kompleks untuk join scan_records, schools, dan log terakhir. Record
+// yang PDF-nya belum sampai storage (masih staging) gak ikut.
+const recordsQuery = `
 		SELECT 
-			sr.id, sr.npsn, sr.path, sr.created_at, 
+			sr.id, sr.npsn, sr.doc_key, sr.version, sr.is_current, sr.path, sr.created_at, 
 			s.nama_sekolah, s.kode,
 			l.sn_bapp, l.hasil_cek
 		FROM scan_records sr
@@ -265,9 +425,21 @@ func recordsHandler(w http.ResponseWriter, r *http.Request) {
 				SELECT MAX(id) as id FROM logs GROUP BY npsn
 			) l2 ON l1.id = l2.id
 		) l ON sr.npsn = l.npsn
-		WHERE 1=1
+		WHERE sr.staging_path = ''
 	`
 
+func recordsHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	searchNPSN := r.URL.Query().Get("npsn")
+	var records []dbclient.Record
+
+	// Cuma versi current, versi lama lewat /records/history
+	query := recordsQuery + " AND sr.is_current = 1"
+
 	args := []interface{}{}
 
 	if searchNPSN != "" {
@@ -291,14 +463,6 @@ func recordsHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-type IsApprovedResult struct {
-	HasilCek    string `json:"hasil_cek"`
-	NPSN        string `json:"npsn"`
-	SNBapp      string `json:"sn_bapp" gorm:"column:sn_bapp"`
-	NamaSekolah string `json:"nama_sekolah" gorm:"column:nama_sekolah"`
-	Kode        string `json:"kode" gorm:"column:kode"`
-}
-
 func isApprovedHandler(w http.ResponseWriter, r *http.Request) {
 	enableCors(&w)
 	if r.Method == "OPTIONS" {
@@ -314,7 +478,7 @@ func isApprovedHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var results []IsApprovedResult
+	var results []dbclient.ApprovalResult
 	var err error
 
 	// Query raw SQL based on parameter
@@ -332,8 +496,8 @@ func isApprovedHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Deduplication (Client side logic migration: unique NPSN, take first)
-	uniqueMap := make(map[string]IsApprovedResult)
-	var uniqueLogs []IsApprovedResult
+	uniqueMap := make(map[string]dbclient.ApprovalResult)
+	var uniqueLogs []dbclient.ApprovalResult
 
 	for _, row := range results {
 		if _, exists := uniqueMap[row.NPSN]; !exists {
@@ -360,38 +524,52 @@ func deleteHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req DeleteRequest
+	var req dbclient.DeleteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Invalid request payload"})
 		return
 	}
 
-	if req.NPSN == "" {
+	npsn, err := dbclient.ParseNPSN(req.NPSN)
+	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "NPSN is required"})
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": err.Error()})
 		return
 	}
 
-	storageDir := os.Getenv("SCAN_STORAGE_PATH")
-	if storageDir == "" {
-		storageDir = "./scans"
+	// NPSN udah divalidasi (gak ada _ atau separator), jadi prefix ini cuma kena file sekolah ini
+	keys, err := store.List(r.Context(), string(npsn)+"_")
+	if err != nil {
+		// Jangan hapus row kalau file-nya gak ketahuan, nanti jadi file yatim di storage
+		log.Println("Error list storage:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Gagal membaca storage, tidak ada data yang dihapus"})
+		return
 	}
-
-	files, _ := os.ReadDir(storageDir)
-	deletedFilesCount := 0
-	prefix := req.NPSN + "_"
-
-	for _, file := range files {
-		if strings.HasPrefix(file.Name(), prefix) && strings.HasSuffix(file.Name(), ".pdf") {
-			fullPath := filepath.Join(storageDir, file.Name())
-			if err := os.Remove(fullPath); err == nil {
-				deletedFilesCount++
-			}
+	deletedFilesCount, failed := 0, 0
+	for _, key := range keys {
+		if !strings.HasSuffix(key, ".pdf") {
+			continue
+		}
+		if err := store.Delete(r.Context(), key); err == nil {
+			deletedFilesCount++
+		} else {
+			failed++
+			log.Printf("Gagal hapus %s dari storage: %v\n", key, err)
 		}
 	}
+	if failed > 0 {
+		// Row tetap ada biar hapus ulang masih nemu sisa file-nya
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("Gagal hapus %d file dari storage (%d terhapus), record database belum dihapus. Coba lagi.", failed, deletedFilesCount),
+		})
+		return
+	}
 
-	dbResult := database.DB.Unscoped().Where("npsn = ?", req.NPSN).Delete(&database.ScanRecord{})
+	dbResult := database.DB.Unscoped().Where("npsn = ?", string(npsn)).Delete(&database.ScanRecord{})
 
 	if dbResult.Error != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -417,6 +595,7 @@ func exportHandler(w http.ResponseWriter, r *http.Request) {
 		NamaSekolah string
 		Termin      string
 		CreatedAt   string // Tanggal Scan
+		Version     int    // Versi dokumen yang current
 		Path        string // Link Path Scan
 	}
 
@@ -428,9 +607,11 @@ func exportHandler(w http.ResponseWriter, r *http.Request) {
 			s.nama_sekolah, 
 			s.termin, 
 			DATE_FORMAT(sr.created_at, '%Y-%m-%d %H:%i:%s') as created_at, 
+			sr.version,
 			sr.path
 		FROM scan_records sr
 		LEFT JOIN schools s ON sr.npsn = s.npsn
+		WHERE sr.is_current = 1 AND sr.staging_path = ''
 		ORDER BY sr.created_at DESC
 	`
 
@@ -448,7 +629,7 @@ func exportHandler(w http.ResponseWriter, r *http.Request) {
 	}()
 
 	// Header
-	headers := []string{"NPSN", "Nama Sekolah", "Termin", "Tanggal Scan", "Link Path Scan"}
+	headers := []string{"NPSN", "Nama Sekolah", "Termin", "Tanggal Scan", "Versi", "Link Path Scan"}
 	for i, h := range headers {
 		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
 		f.SetCellValue("Sheet1", cell, h)
@@ -460,7 +641,8 @@ func exportHandler(w http.ResponseWriter, r *http.Request) {
 		f.SetCellValue("Sheet1", fmt.Sprintf("B%d", i+2), row.NamaSekolah)
 		f.SetCellValue("Sheet1", fmt.Sprintf("C%d", i+2), row.Termin)
 		f.SetCellValue("Sheet1", fmt.Sprintf("D%d", i+2), row.CreatedAt)
-		f.SetCellValue("Sheet1", fmt.Sprintf("E%d", i+2), row.Path)
+		f.SetCellValue("Sheet1", fmt.Sprintf("E%d", i+2), row.Version)
+		f.SetCellValue("Sheet1", fmt.Sprintf("F%d", i+2), store.Location(storage.KeyOf(row.Path)))
 	}
 
 	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
@@ -474,28 +656,42 @@ func exportHandler(w http.ResponseWriter, r *http.Request) {
 func main() {
 	http.HandleFunc("/delete", deleteHandler)
 	http.HandleFunc("/save", saveHandler)
+	http.HandleFunc("/save/upload", uploadHandler)
 	http.HandleFunc("/stats", statsHandler)
 	http.HandleFunc("/records", recordsHandler)
+	http.HandleFunc("/records/history", historyHandler)
+	http.HandleFunc("/records/current", setCurrentHandler)
 	http.HandleFunc("/is-approved", isApprovedHandler)
 	http.HandleFunc("/export", exportHandler)
+	http.HandleFunc("/stations", stationsHandler)
+	http.HandleFunc("/stations/heartbeat", heartbeatHandler)
+	http.HandleFunc("/profiles/config", profileConfigHandler)
 	http.HandleFunc("/", home)
 	database.InitDB()
+	initSaveSlots()
 
-	// Serve Static Files (Scans)
-	storageDir := os.Getenv("SCAN_STORAGE_PATH")
-	if storageDir == "" {
-		storageDir = "./scans"
+	var err error
+	store, err = storage.FromEnv()
+	if err != nil {
+		log.Fatal("Gagal buka storage PDF:", err)
 	}
-	// Pastikan folder ada
-	os.MkdirAll(storageDir, 0755)
+	fmt.Printf("Storage PDF: %s\n", store.Name())
+	recoverStaging()
 
-	fs := http.FileServer(http.Dir(storageDir))
-	http.Handle("/scans/", http.StripPrefix("/scans/", fs))
+	// Serve file scan dari storage (local / s3)
+	http.HandleFunc("/scans/", scansHandler)
 
-	port := ":5000"
+	// API_PORT (di .env / env sistem), default tetap 5000 biar frontend &
+	// db_api_url yang udah ada gak putus. Kalau service db jalan satu PC
+	// dengan Scanner Bridge (juga :5000), set API_PORT ke port lain, mis. 5001.
+	port := os.Getenv("API_PORT")
+	if port == "" {
+		port = "5000"
+	}
+	port = ":" + strings.TrimPrefix(port, ":")
 	fmt.Printf("Database API (Golang) siap di http://localhost%s\n", port)
 
 	if err := http.ListenAndServe(port, nil); err != nil {
-		log.Fatal("Gagal menjalankan server:", err)
+		log.Fatalf("Gagal menjalankan server di port %s (set API_PORT di .env kalau port ini dipakai program lain): %v", port, err)
 	}
 }
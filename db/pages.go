@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"dbclient"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"scanner-bridge/database"
+	"strconv"
+	"strings"
+)
+
+// Halaman dokumen di POST /save & /save/upload. Urutan di request = urutan
+// di PDF, request format lama (image_front / image_back) udah diubah jadi
+// list lewat PageList. Semua gambar di-decode beneran dulu biar PDF gak
+// keisi gambar rusak diam-diam.
+
+const (
+	maxPages     = 100
+	maxPageBytes = 20 << 20  // Per gambar
+	maxPageSide  = 15000     // Piksel, jaga-jaga decompression bomb
+	maxSaveBytes = 200 << 20 // Satu request upload
+	// Total piksel per halaman (~A4 600 dpi), 15000x15000 RGBA masih ~900 MB
+	// kalau di-decode
+	maxPagePixels = 40 << 20
+)
+
+// pageImage: halaman yang gambarnya udah lolos cek
+type pageImage struct {
+	dbclient.Page        // Image gak dipakai lagi, gambarnya di Data
+	Data          []byte // Bytes asli JPEG / PNG
+	Type          string // JPG / PNG, buat gofpdf
+	SHA256        string // Hex, dihitung dari Data
+}
+
+func newPageImage(p dbclient.Page, data []byte, typ string) pageImage {
+	sum := sha256.Sum256(data)
+	p.Image = ""
+	return pageImage{Page: p, Data: data, Type: typ, SHA256: hex.EncodeToString(sum[:])}
+}
+
+func validatePage(p dbclient.Page) error {
+	if p.Side != "" && p.Side != dbclient.PageSideFront && p.Side != dbclient.PageSideBack {
+		return fmt.Errorf("side harus front atau back")
+	}
+	if p.Sheet < 0 {
+		return fmt.Errorf("sheet tidak valid")
+	}
+	return nil
+}
+
+// checkImage: pastiin data beneran JPEG / PNG utuh, balikin tipe buat gofpdf
+// dan ukurannya
+func checkImage(data []byte) (string, image.Config, error) {
+	var cfg image.Config
+	if len(data) == 0 {
+		return "", cfg, errors.New("gambar kosong")
+	}
+	if len(data) > maxPageBytes {
+		return "", cfg, fmt.Errorf("ukuran gambar lebih dari %d MB", maxPageBytes>>20)
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", cfg, errors.New("bukan gambar JPEG / PNG yang valid")
+	}
+	var typ string
+	switch format {
+	case "jpeg":
+		typ = "JPG"
+	case "png":
+		typ = "PNG"
+		if err := checkPNG(data); err != nil {
+			return "", cfg, err
+		}
+	default:
+		return "", cfg, fmt.Errorf("format %s tidak didukung, harus JPEG / PNG", format)
+	}
+	if cfg.Width > maxPageSide || cfg.Height > maxPageSide {
+		return "", cfg, fmt.Errorf("resolusi %dx%d terlalu besar (maksimal %d piksel per sisi)", cfg.Width, cfg.Height, maxPageSide)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxPagePixels {
+		return "", cfg, fmt.Errorf("resolusi %dx%d terlalu besar (maksimal %d megapiksel)", cfg.Width, cfg.Height, maxPagePixels>>20)
+	}
+	// Decode penuh biar file yang kepotong ketahuan
+	if _, _, err := image.Decode(bytes.NewReader(data)); err != nil {
+		return "", cfg, fmt.Errorf("gambar rusak: %v", err)
+	}
+	return typ, cfg, nil
+}
+
+// checkPNG: gofpdf gak bisa nulis PNG 16 bit / interlaced ke PDF, ditolak di
+// depan daripada gagal waktu bikin PDF. data udah lolos DecodeConfig, jadi
+// IHDR pasti chunk pertama (byte 24 = bit depth, 28 = interlace).
+func checkPNG(data []byte) error {
+	if depth := data[24]; depth > 8 {
+		return fmt.Errorf("PNG %d bit tidak didukung, simpan ulang sebagai PNG 8 bit atau JPEG", depth)
+	}
+	if data[28] != 0 {
+		return errors.New("PNG interlaced tidak didukung, simpan ulang tanpa interlace atau sebagai JPEG")
+	}
+	return nil
+}
+
+// verifyMeta: metadata dari bridge harus cocok sama gambar yang beneran
+// diterima, kalau gak berarti gambarnya berubah di jalan (atau meta-nya
+// nempel ke halaman yang salah)
+func verifyMeta(meta *dbclient.PageMeta, img pageImage, cfg image.Config) error {
+	if meta == nil {
+		return nil
+	}
+	if meta.SHA256 == "" {
+		return errors.New("sha256 wajib diisi kalau meta dikirim")
+	}
+	if !strings.EqualFold(meta.SHA256, img.SHA256) {
+		return fmt.Errorf("sha256 tidak cocok dengan gambar (meta %s, gambar %s)", meta.SHA256, img.SHA256)
+	}
+	if meta.Bytes != 0 && meta.Bytes != len(img.Data) {
+		return fmt.Errorf("bytes %d tidak cocok dengan ukuran gambar %d", meta.Bytes, len(img.Data))
+	}
+	if (meta.Width != 0 || meta.Height != 0) && (meta.Width != cfg.Width || meta.Height != cfg.Height) {
+		return fmt.Errorf("ukuran %dx%d tidak cocok dengan gambar %dx%d", meta.Width, meta.Height, cfg.Width, cfg.Height)
+	}
+	return nil
+}
+
+// pageProvenance: yang dicatat di scan_records.pages buat tiap halaman PDF
+func pageProvenance(pages []pageImage) database.PageList {
+	list := make(database.PageList, 0, len(pages))
+	for i, p := range pages {
+		list = append(list, dbclient.PageProvenance{
+			Page:   i + 1,
+			Sheet:  p.Sheet,
+			Side:   p.Side,
+			SHA256: p.SHA256,
+			Bytes:  len(p.Data),
+			Meta:   p.Meta,
+		})
+	}
+	return list
+}
+
+// decodeJSONPages: base64 di body JSON POST /save jadi pageImage
+func decodeJSONPages(req dbclient.SaveRequest) ([]pageImage, []dbclient.PartError) {
+	pages := req.PageList()
+	if len(pages) == 0 {
+		return nil, []dbclient.PartError{{Part: "pages", Message: "Dokumen minimal harus punya satu halaman"}}
+	}
+	if len(pages) > maxPages {
+		return nil, []dbclient.PartError{{Part: "pages", Message: fmt.Sprintf("Maksimal %d halaman per dokumen", maxPages)}}
+	}
+
+	var images []pageImage
+	var errs []dbclient.PartError
+	for i, p := range pages {
+		part, metaPart := "pages["+strconv.Itoa(i)+"]", "pages["+strconv.Itoa(i)+"].meta"
+		if len(req.Pages) == 0 {
+			part, metaPart = "image_"+p.Side, "image_"+p.Side+"_meta"
+		}
+		if err := validatePage(p); err != nil {
+			errs = append(errs, dbclient.PartError{Part: part, Message: err.Error()})
+			continue
+		}
+
+		b64Str := p.Image
+		if idx := strings.Index(b64Str, ","); idx != -1 {
+			b64Str = b64Str[idx+1:]
+		}
+		data, err := base64.StdEncoding.DecodeString(b64Str)
+		if err != nil {
+			errs = append(errs, dbclient.PartError{Part: part, Message: "base64 tidak valid"})
+			continue
+		}
+		typ, cfg, err := checkImage(data)
+		if err != nil {
+			errs = append(errs, dbclient.PartError{Part: part, Message: err.Error()})
+			continue
+		}
+		img := newPageImage(p, data, typ)
+		if err := verifyMeta(p.Meta, img, cfg); err != nil {
+			errs = append(errs, dbclient.PartError{Part: metaPart, Message: err.Error()})
+			continue
+		}
+		images = append(images, img)
+	}
+	return images, errs
+}
+
+// writePartErrors: 400 dengan detail error per halaman / part
+func writePartErrors(w http.ResponseWriter, errs []dbclient.PartError) {
+	msg := fmt.Sprintf("%s: %s", errs[0].Part, errs[0].Message)
+	if len(errs) > 1 {
+		msg = fmt.Sprintf("Ada %d error validasi, pertama %s", len(errs), msg)
+	}
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"message": msg,
+		"errors":  errs,
+	})
+}
+
+// pageTitle: judul di atas gambar tiap halaman PDF
+func pageTitle(i int, p dbclient.Page) string {
+	title := "Halaman " + strconv.Itoa(i+1)
+	if p.Label != "" {
+		return title + " - " + p.Label
+	}
+	var side string
+	switch p.Side {
+	case dbclient.PageSideFront:
+		side = "Depan"
+	case dbclient.PageSideBack:
+		side = "Belakang"
+	}
+	switch {
+	case p.Sheet > 0 && side != "":
+		return fmt.Sprintf("%s - Lembar %d %s", title, p.Sheet, side)
+	case p.Sheet > 0:
+		return fmt.Sprintf("%s - Lembar %d", title, p.Sheet)
+	case side != "":
+		return title + " - " + side
+	}
+	return title
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"dbclient"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func testJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeJSONPagesMeta(t *testing.T) {
+	data := testJPEG(t, 30, 40)
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	uri := "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(data)
+
+	tests := []struct {
+		name string
+		meta *dbclient.PageMeta
+		want string // Potongan pesan error, kosong = lolos
+	}{
+		{"tanpa meta", nil, ""},
+		{"cocok", &dbclient.PageMeta{SHA256: hash, Width: 30, Height: 40, Bytes: len(data), StationID: "st-1"}, ""},
+		{"hash huruf besar", &dbclient.PageMeta{SHA256: strings.ToUpper(hash)}, ""},
+		{"hash kosong", &dbclient.PageMeta{Width: 30, Height: 40}, "sha256 wajib diisi"},
+		{"hash beda", &dbclient.PageMeta{SHA256: strings.Repeat("0", 64)}, "sha256 tidak cocok"},
+		{"ukuran beda", &dbclient.PageMeta{SHA256: hash, Width: 40, Height: 30}, "ukuran 40x30 tidak cocok"},
+		{"bytes beda", &dbclient.PageMeta{SHA256: hash, Bytes: 1}, "bytes 1 tidak cocok"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := dbclient.SaveRequest{Pages: []dbclient.Page{{Image: uri, Sheet: 1, Side: dbclient.PageSideFront, Meta: tt.meta}}}
+			pages, errs := decodeJSONPages(req)
+			if tt.want == "" {
+				if len(errs) > 0 || len(pages) != 1 {
+					t.Fatalf("harusnya lolos, errs %+v", errs)
+				}
+				prov := pageProvenance(pages)
+				if prov[0].SHA256 != hash || prov[0].Bytes != len(data) || prov[0].Meta != tt.meta {
+					t.Errorf("provenance %+v", prov[0])
+				}
+				return
+			}
+			if len(errs) != 1 || errs[0].Part != "pages[0].meta" || !strings.Contains(errs[0].Message, tt.want) {
+				t.Fatalf("errs = %+v, harusnya pages[0].meta %q", errs, tt.want)
+			}
+		})
+	}
+}
+
+func TestUploadPagesMeta(t *testing.T) {
+	data := testJPEG(t, 30, 40)
+	files := map[string][]byte{"page_1": data, "page_2": data}
+	fields := map[string]string{
+		"page_1_meta": `{"sha256":"` + strings.Repeat("a", 64) + `"}`,
+		"page_2_meta": `{"sha256":`,
+	}
+	_, errs := uploadPages(fields, files)
+	if len(errs) != 2 {
+		t.Fatalf("errs = %+v, harusnya 2", errs)
+	}
+	if errs[0].Part != "page_1_meta" || !strings.Contains(errs[0].Message, "sha256 tidak cocok") {
+		t.Errorf("page_1: %+v", errs[0])
+	}
+	if errs[1].Part != "page_2_meta" || !strings.Contains(errs[1].Message, "bukan JSON") {
+		t.Errorf("page_2: %+v", errs[1])
+	}
+}
+
+// pngHeader: PNG 8 bit RGB yang cuma punya IHDR, cukup buat DecodeConfig
+func pngHeader(w, h uint32) []byte {
+	return pngHeaderWith(w, h, 8, 0)
+}
+
+func pngHeaderWith(w, h uint32, depth, interlace byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("\x89PNG\r\n\x1a\n")
+	ihdr := make([]byte, 17)
+	copy(ihdr, "IHDR")
+	binary.BigEndian.PutUint32(ihdr[4:], w)
+	binary.BigEndian.PutUint32(ihdr[8:], h)
+	ihdr[12], ihdr[13] = depth, 2 // RGB
+	ihdr[16] = interlace
+	binary.Write(&b, binary.BigEndian, uint32(13))
+	b.Write(ihdr)
+	binary.Write(&b, binary.BigEndian, crc32.ChecksumIEEE(ihdr))
+	return b.Bytes()
+}
+
+func TestCheckImageTooLarge(t *testing.T) {
+	tests := []struct {
+		w, h uint32
+		want string
+	}{
+		{maxPageSide + 1, 100, "piksel per sisi"},
+		{maxPageSide, maxPageSide, "megapiksel"},
+		{8000, 8000, "megapiksel"},
+	}
+	for _, tt := range tests {
+		_, _, err := checkImage(pngHeader(tt.w, tt.h))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%dx%d: err = %v, harusnya berisi %q", tt.w, tt.h, err, tt.want)
+		}
+	}
+	// Di bawah batas lolos ke decode penuh, gambarnya kepotong jadi rusak
+	if _, _, err := checkImage(pngHeader(4960, 7016)); err == nil || !strings.Contains(err.Error(), "gambar rusak") {
+		t.Errorf("A4 600 dpi: err = %v, harusnya lolos cek ukuran", err)
+	}
+}
+
+func TestCheckImagePNGGofpdf(t *testing.T) {
+	var deep bytes.Buffer
+	if err := png.Encode(&deep, image.NewRGBA64(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	var flat bytes.Buffer
+	if err := png.Encode(&flat, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"16 bit", deep.Bytes(), "16 bit"},
+		{"interlaced", pngHeaderWith(100, 100, 8, 1), "interlaced"},
+		{"8 bit", flat.Bytes(), ""},
+	}
+	for _, tt := range tests {
+		_, _, err := checkImage(tt.data)
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: err = %v, harusnya lolos", tt.name, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%s: err = %v, harusnya berisi %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"crypto/subtle"
+	"dbclient"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"scanner-bridge/database"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Profile terpusat: definisi profile NAPS2 + aturan post-processing yang
+// ditarik semua bridge lewat GET /profiles/config. Publish versi baru lewat
+// POST /profiles/config (wajib token admin), versi lama tetap disimpen buat
+// rollback / audit.
+
+// Nilai yang dikenal NAPS2 di profiles.xml
+var (
+	naps2DPIs         = map[int]bool{100: true, 150: true, 200: true, 300: true, 400: true, 600: true, 800: true, 1200: true}
+	naps2PaperSources = map[string]bool{"Glass": true, "Feeder": true, "Duplex": true}
+	naps2BitDepths    = map[string]bool{"C24Bit": true, "Grayscale": true, "BlackWhite": true}
+)
+
+func validateProfiles(d dbclient.ProfileDefinition) error {
+	seen := make(map[string]bool)
+	for i, p := range d.Profiles {
+		if p.Name == "" {
+			return fmt.Errorf("profile ke-%d: name wajib diisi", i+1)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("profile %q dobel", p.Name)
+		}
+		seen[p.Name] = true
+		if p.DPI != 0 && !naps2DPIs[p.DPI] {
+			return fmt.Errorf("profile %q: dpi %d gak didukung NAPS2", p.Name, p.DPI)
+		}
+		if p.PaperSource != "" && !naps2PaperSources[p.PaperSource] {
+			return fmt.Errorf("profile %q: paper_source harus Glass, Feeder, atau Duplex", p.Name)
+		}
+		if p.BitDepth != "" && !naps2BitDepths[p.BitDepth] {
+			return fmt.Errorf("profile %q: bit_depth harus C24Bit, Grayscale, atau BlackWhite", p.Name)
+		}
+	}
+	return nil
+}
+
+func profileConfigHandler(w http.ResponseWriter, r *http.Request) {
+	// CORS cuma buat GET. Publish ngubah config semua bridge, jadi gak boleh
+	// bisa ditembak dari halaman web mana pun (preflight-nya sengaja ditolak).
+	if r.Method == "GET" {
+		enableCors(&w)
+	}
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		getProfileConfig(w, r)
+	case "POST":
+		if !adminAuthorized(w, r) {
+			return
+		}
+		publishProfileConfig(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GET /profiles/config (versi terbaru) atau ?version=N. Belum ada yang
+// dipublish = version 0 dengan profiles kosong, bridge pakai setting lokal.
+func getProfileConfig(w http.ResponseWriter, r *http.Request) {
+	var cfg database.ProfileConfig
+	query := database.DB.Order("version DESC")
+	if v := r.URL.Query().Get("version"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Parameter version harus angka"})
+			return
+		}
+		query = query.Where("version = ?", n)
+	}
+
+	res := dbclient.ProfileConfig{ProfileDefinition: dbclient.ProfileDefinition{Profiles: []dbclient.ManagedProfile{}}}
+	err := query.First(&cfg).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound) && r.URL.Query().Get("version") != "":
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Versi profile tidak ditemukan"})
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		// Belum pernah publish
+	case err != nil:
+		log.Println("Error fetching profile config:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Gagal ambil profile terpusat"})
+		return
+	default:
+		var def dbclient.ProfileDefinition
+		if err := json.Unmarshal([]byte(cfg.Definition), &def); err != nil {
+			log.Printf("Profile config versi %d rusak: %v\n", cfg.Version, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Data profile terpusat rusak"})
+			return
+		}
+		res.Version = int(cfg.Version)
+		res.Note = cfg.Note
+		res.CreatedAt = &cfg.CreatedAt
+		if def.Profiles != nil {
+			res.Profiles = def.Profiles
+		}
+		res.Quality = def.Quality
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    res,
+	})
+}
+
+// adminAuthorized: request wajib bawa "Authorization: Bearer <ADMIN_TOKEN>".
+// ADMIN_TOKEN kosong = endpoint admin ditutup sama sekali.
+func adminAuthorized(w http.ResponseWriter, r *http.Request) bool {
+	token := os.Getenv("ADMIN_TOKEN")
+	if token == "" {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Publish profile dinonaktifkan, set ADMIN_TOKEN di service db dulu"})
+		return false
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+		log.Printf("Publish profile ditolak dari %s: token admin salah / kosong\n", r.RemoteAddr)
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Token admin salah atau tidak dikirim"})
+		return false
+	}
+	return true
+}
+
+// POST /profiles/config: publish versi baru, semua bridge ikut di sync berikutnya
+func publishProfileConfig(w http.ResponseWriter, r *http.Request) {
+	var req dbclient.PublishProfilesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Request tidak valid"})
+		return
+	}
+	if err := validateProfiles(req.ProfileDefinition); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": err.Error()})
+		return
+	}
+
+	def, err := json.Marshal(req.ProfileDefinition)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Gagal menyimpan profile terpusat"})
+		return
+	}
+	cfg := database.ProfileConfig{Definition: string(def), Note: req.Note}
+	if err := database.DB.Create(&cfg).Error; err != nil {
+		log.Println("Gagal simpan profile config:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Gagal menyimpan profile terpusat"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"version": cfg.Version,
+		"message": fmt.Sprintf("Profile terpusat versi %d dipublish", cfg.Version),
+	})
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"dbclient"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"scanner-bridge/database"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// History versi dokumen: scan ulang (mode new_version) gak ngehapus PDF lama,
+// cuma mindahin tanda current. /records & /export cuma nampilin yang current.
+
+// errVersionTaken: nomor versi keburu dipakai simpan lain yang barengan
+var errVersionTaken = errors.New("versi dokumen sudah dipakai")
+
+// errStillStaging: PDF versi ini belum sampai storage, belum boleh jadi current
+var errStillStaging = errors.New("versi dokumen masih diproses")
+
+// isVersionConflict: transaksi kalah rebutan sama simpan / ganti versi lain
+// di dokumen yang sama. 1062 = kena unique index (npsn, doc_key, version),
+// 1213 = deadlock waktu dua transaksi ngunci dokumen yang sama.
+func isVersionConflict(err error) bool {
+	var myErr *mysql.MySQLError
+	if !errors.As(err, &myErr) {
+		return false
+	}
+	return myErr.Number == 1062 || myErr.Number == 1213
+}
+
+// lockDocument kunci semua versi dokumen sampai transaksi selesai, biar ganti
+// versi current gak balapan sama simpan / ganti current lain
+func lockDocument(tx *gorm.DB, npsn, docKey string) error {
+	var ids []uint
+	return tx.Model(&database.ScanRecord{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("npsn = ? AND doc_key = ?", npsn, docKey).Pluck("id", &ids).Error
+}
+
+// GET /records/history?npsn=...&doc_key=... semua versi (plus hash &
+// metadata tiap halaman), terbaru dulu
+func historyHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	npsn, err := dbclient.ParseNPSN(r.URL.Query().Get("npsn"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": err.Error()})
+		return
+	}
+
+	query := recordsQuery + " AND sr.npsn = ?"
+	args := []interface{}{string(npsn)}
+	if docKey, ok := r.URL.Query()["doc_key"]; ok {
+		query += " AND sr.doc_key = ?"
+		args = append(args, docKey[0])
+	}
+	query += " ORDER BY sr.doc_key, sr.version DESC"
+
+	var records []dbclient.Record
+	if err := database.DB.Raw(query, args...).Scan(&records).Error; err != nil {
+		log.Println("Error fetching record history:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Gagal ambil history dokumen"})
+		return
+	}
+
+	// Provenance per halaman, buat bukti PDF versi mana dari scan yang mana
+	ids := make([]uint, 0, len(records))
+	for _, rec := range records {
+		ids = append(ids, rec.ID)
+	}
+	var pages []database.ScanRecord
+	if len(ids) > 0 {
+		if err := database.DB.Select("id", "pages").Where("id IN ?", ids).Find(&pages).Error; err != nil {
+			log.Println("Error fetching page provenance:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Gagal ambil history dokumen"})
+			return
+		}
+	}
+	byID := make(map[uint]database.PageList, len(pages))
+	for _, p := range pages {
+		byID[p.ID] = p.Pages
+	}
+	for i := range records {
+		records[i].Pages = byID[records[i].ID]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    records,
+	})
+}
+
+// POST /records/current {"id": 12}: balik ke versi lama tanpa scan ulang
+func setCurrentHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req dbclient.SetCurrentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Request tidak valid, id wajib diisi"})
+		return
+	}
+
+	var rec database.ScanRecord
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&rec, req.ID).Error; err != nil {
+			return err
+		}
+		if err := lockDocument(tx, rec.NPSN, rec.DocKey); err != nil {
+			return err
+		}
+		// Baca ulang yang udah kekunci, bisa aja barusan dihapus / selesai staging
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&rec, req.ID).Error; err != nil {
+			return err
+		}
+		if rec.StagingPath != "" {
+			return errStillStaging
+		}
+		if err := tx.Model(&database.ScanRecord{}).
+			Where("npsn = ? AND doc_key = ? AND id <> ?", rec.NPSN, rec.DocKey, rec.ID).
+			Update("is_current", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&rec).Update("is_current", true).Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Record tidak ditemukan"})
+		return
+	case errors.Is(err, errStillStaging):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Versi ini masih diproses, coba lagi sebentar lagi"})
+		return
+	case isVersionConflict(err):
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Dokumen ini barusan diubah dari tempat lain, cek history lalu coba lagi."})
+		return
+	case err != nil:
+		log.Println("Gagal ganti versi current:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Gagal ganti versi current"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Versi %d dokumen %s sekarang jadi versi current", rec.Version, rec.NPSN),
+	})
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestIsVersionConflict(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry '123-ABC-2' for key 'idx_scan_records_doc_version'"}, true},
+		{&mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}, true},
+		{fmt.Errorf("simpan: %w", &mysql.MySQLError{Number: 1062}), true},
+		{&mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row"}, false},
+		{errors.New("Duplicate entry 1062"), false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := isVersionConflict(tt.err); got != tt.want {
+			t.Errorf("isVersionConflict(%v) = %v, harusnya %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"scanner-bridge/storage"
+	"strconv"
+	"strings"
+)
+
+// Storage PDF yang dipakai service (diisi di main dari STORAGE_BACKEND)
+var store storage.Storage
+
+// GET /scans/<key>: ganti http.FileServer biar bisa dari storage mana pun
+func scansHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		return
+	}
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/scans/")
+	if storage.CheckKey(key) != nil {
+		http.NotFound(w, r)
+		return
+	}
+	obj, err := store.Open(r.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("Gagal buka %s dari storage %s: %v\n", key, store.Name(), err)
+		http.Error(w, "Gagal membaca file scan", http.StatusBadGateway)
+		return
+	}
+	defer obj.Close()
+
+	w.Header().Set("Content-Type", "application/pdf")
+	// File lokal bisa di-seek, sekalian dukung Range & If-Modified-Since
+	if rs, ok := obj.ReadCloser.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, key, obj.ModTime, rs)
+		return
+	}
+	if obj.Size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(obj.Size, 10))
+	}
+	if !obj.ModTime.IsZero() {
+		w.Header().Set("Last-Modified", obj.ModTime.UTC().Format(http.TimeFormat))
+	}
+	if r.Method == "HEAD" {
+		return
+	}
+	io.Copy(w, obj)
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"scanner-bridge/database"
+	"scanner-bridge/storage"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+	"gorm.io/gorm"
+)
+
+// Simpan PDF dibikin atomic:
+//  1. PDF ditulis ke folder staging lokal (file unik, di-fsync)
+//  2. Row scan_records di-commit dalam transaksi, staging_path diisi,
+//     belum current
+//  3. File dipindah ke storage (local: rename, s3: PUT lalu file staging dihapus)
+//  4. staging_path dikosongin, tanda current pindah ke versi ini
+//
+// Kalau proses mati di tengah jalan, recoverStaging waktu start nyelesain
+// yang udah commit dan buang staging yang belum. Kalau ada beberapa instance
+// (DB & folder staging dipakai bareng), tiap instance cuma ngurus record
+// yang dia tulis sendiri, lihat stagingHost.
+
+const (
+	stagingDirName = ".staging"
+	// Record / file staging lebih muda dari ini bisa aja lagi diproses
+	// instance lain, jangan disentuh
+	stagingGrace = time.Hour
+)
+
+// stagingDir: SCAN_STAGING_PATH, default SCAN_STORAGE_PATH/.staging (satu
+// filesystem dengan storage lokal biar pindahnya cukup rename)
+func stagingDir() string {
+	if dir := os.Getenv("SCAN_STAGING_PATH"); dir != "" {
+		return dir
+	}
+	return filepath.Join(storage.LocalPath(), stagingDirName)
+}
+
+// stagingHost: SCAN_INSTANCE_ID, default hostname. Set manual kalau hostname
+// berubah tiap restart (mis. container) biar simpan yang kepotong tetap
+// di-recover instance penggantinya.
+func stagingHost() string {
+	if id := os.Getenv("SCAN_INSTANCE_ID"); id != "" {
+		return id
+	}
+	host, _ := os.Hostname()
+	return host
+}
+
+// inStagingDir: path ada di folder staging instance ini
+func inStagingDir(path string) bool {
+	rel, err := filepath.Rel(stagingDir(), path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// writeStaging tulis PDF ke file staging baru, balikin path-nya
+func writeStaging(pdf *gofpdf.Fpdf, fileNameBase string) (string, error) {
+	dir := stagingDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(dir, fileNameBase+"-*.pdf")
+	if err != nil {
+		return "", err
+	}
+	if err := pdf.Output(f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	// Pastiin isi PDF udah di disk sebelum row-nya di-commit
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// finishStaging: pindahin file staging ke storage lalu tandain record-nya
+// udah tersimpan (lihat markStored)
+func finishStaging(ctx context.Context, rec *database.ScanRecord) error {
+	if err := store.PutFile(ctx, storage.KeyOf(rec.Path), rec.StagingPath); err != nil {
+		return err
+	}
+	if err := markStored(rec); err != nil {
+		// File udah di tempatnya, sisa tanda staging dibersihin recoverStaging
+		log.Printf("WARNING: Gagal kosongin staging_path record %d: %v\n", rec.ID, err)
+	}
+	return nil
+}
+
+// markStored: PDF record udah di storage. staging_path dikosongin dan record
+// jadi versi current, kecuali versi yang lebih baru keburu tersimpan duluan.
+// Versi lama baru dilepas di sini biar dokumen gak pernah kosong tanpa PDF.
+func markStored(rec *database.ScanRecord) error {
+	current := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockDocument(tx, rec.NPSN, rec.DocKey); err != nil {
+			return err
+		}
+		var newer int64
+		if err := tx.Model(&database.ScanRecord{}).
+			Where("npsn = ? AND doc_key = ? AND version > ? AND staging_path = ''", rec.NPSN, rec.DocKey, rec.Version).
+			Count(&newer).Error; err != nil {
+			return err
+		}
+		if newer > 0 {
+			return tx.Model(rec).Updates(map[string]interface{}{"is_current": false, "staging_path": ""}).Error
+		}
+		if err := tx.Model(&database.ScanRecord{}).
+			Where("npsn = ? AND doc_key = ? AND id <> ?", rec.NPSN, rec.DocKey, rec.ID).
+			Update("is_current", false).Error; err != nil {
+			return err
+		}
+		current = true
+		return tx.Model(rec).Updates(map[string]interface{}{"is_current": true, "staging_path": ""}).Error
+	})
+	if err != nil {
+		return err
+	}
+	rec.IsCurrent = current
+	rec.StagingPath = ""
+	return nil
+}
+
+// discardRecord hapus row yang PDF-nya gak pernah sampai ke tempatnya. Kalau
+// itu versi current (record staging sebelum current dipindah di markStored),
+// versi terbaru yang PDF-nya udah tersimpan jadi current lagi.
+func discardRecord(rec database.ScanRecord) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockDocument(tx, rec.NPSN, rec.DocKey); err != nil {
+			return err
+		}
+		if err := tx.Delete(&database.ScanRecord{}, rec.ID).Error; err != nil {
+			return err
+		}
+		if !rec.IsCurrent {
+			return nil
+		}
+		var prev database.ScanRecord
+		err := tx.Where("npsn = ? AND doc_key = ? AND staging_path = ''", rec.NPSN, rec.DocKey).Order("version DESC").First(&prev).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&prev).Update("is_current", true).Error
+	})
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// recoverStaging dipanggil sekali waktu start, sebelum server nerima request
+func recoverStaging() {
+	// Punya instance ini pasti gak ada yang lagi jalan (baru start). Record
+	// lama tanpa staging_host cuma diambil kalau udah lewat stagingGrace.
+	var pending []database.ScanRecord
+	if err := database.DB.Where("staging_path <> '' AND (staging_host = ? OR (staging_host = '' AND created_at < ?))",
+		stagingHost(), time.Now().Add(-stagingGrace)).Find(&pending).Error; err != nil {
+		log.Println("WARNING: Gagal cek simpan yang belum selesai:", err)
+		return
+	}
+
+	ctx := context.Background()
+	keep := make(map[string]bool)
+	completed, discarded := 0, 0
+	for _, rec := range pending {
+		if !inStagingDir(rec.StagingPath) {
+			// Folder staging-nya beda (config lama / instance lain), jangan dihapus dari sini
+			log.Printf("WARNING: Record %d staging di %s, di luar %s, dilewati\n", rec.ID, rec.StagingPath, stagingDir())
+			continue
+		}
+		if fileExists(rec.StagingPath) {
+			if err := finishStaging(ctx, &rec); err != nil {
+				log.Printf("WARNING: Gagal nyelesain simpan record %d (%s): %v\n", rec.ID, rec.Path, err)
+				keep[filepath.Clean(rec.StagingPath)] = true
+				continue
+			}
+			completed++
+			continue
+		}
+
+		stored, err := store.Exists(ctx, storage.KeyOf(rec.Path))
+		if err != nil {
+			// Storage lagi gak bisa dicek, biarin pending sampai start berikutnya
+			log.Printf("WARNING: Gagal cek %s di storage %s: %v\n", rec.Path, store.Name(), err)
+			continue
+		}
+		if stored {
+			// Udah sampai storage, tinggal tanda staging & current-nya
+			if err := markStored(&rec); err != nil {
+				log.Printf("WARNING: Gagal kosongin staging_path record %d: %v\n", rec.ID, err)
+				continue
+			}
+			completed++
+			continue
+		}
+
+		log.Printf("Record %d (%s) gak punya file PDF, dihapus\n", rec.ID, rec.Path)
+		if err := discardRecord(rec); err != nil {
+			log.Printf("WARNING: Gagal hapus record %d: %v\n", rec.ID, err)
+			continue
+		}
+		discarded++
+	}
+
+	// Sisa file staging = simpan yang mati sebelum commit. Yang masih baru
+	// dibiarin, bisa aja punya instance lain yang lagi nyimpen.
+	removed := 0
+	entries, _ := os.ReadDir(stagingDir())
+	for _, e := range entries {
+		path := filepath.Join(stagingDir(), e.Name())
+		if e.IsDir() || keep[filepath.Clean(path)] {
+			continue
+		}
+		if info, err := e.Info(); err != nil || time.Since(info.ModTime()) < stagingGrace {
+			continue
+		}
+		if err := os.Remove(path); err == nil {
+			removed++
+		}
+	}
+
+	if completed+discarded+removed > 0 {
+		fmt.Printf("Recovery simpan: %d diselesaikan, %d record tanpa file dihapus, %d file staging dibuang\n", completed, discarded, removed)
+	}
+}
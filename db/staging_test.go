@@ -0,0 +1,27 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestInStagingDir(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("SCAN_STAGING_PATH", dir)
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{filepath.Join(dir, "123_ABC-1.pdf"), true},
+		{filepath.Join(dir, "..", "123_ABC.pdf"), false},
+		{filepath.Join(dir, "..", filepath.Base(dir)+"-lain", "x.pdf"), false},
+		{dir, false},
+		{"/srv/host-lain/.staging/123_ABC-1.pdf", false},
+		{"..staging.pdf", false},
+	}
+	for _, tt := range tests {
+		if got := inStagingDir(tt.path); got != tt.want {
+			t.Errorf("inStagingDir(%q) = %v, harusnya %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"dbclient"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"scanner-bridge/database"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// Registry station scanner-bridge. Tiap bridge kirim heartbeat berkala,
+// dashboard tinggal GET /stations buat liat siapa yang online.
+
+const defaultHeartbeatSeconds = 60
+
+func heartbeatHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req dbclient.Heartbeat
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Request tidak valid"})
+		return
+	}
+	req.StationID = strings.TrimSpace(req.StationID)
+	if req.StationID == "" || len(req.StationID) > 64 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "station_id wajib diisi (maks 64 karakter)"})
+		return
+	}
+	if req.IntervalSeconds <= 0 {
+		req.IntervalSeconds = defaultHeartbeatSeconds
+	}
+
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+
+	station := database.Station{
+		ID:              req.StationID,
+		Name:            req.Name,
+		Hostname:        req.Hostname,
+		Version:         req.Version,
+		OS:              req.OS,
+		Backends:        req.Backends,
+		Devices:         req.Devices,
+		Profiles:        req.Profiles,
+		ConfigVersion:   req.ConfigVersion,
+		LastError:       req.LastError,
+		LastErrorAt:     req.LastErrorAt,
+		IntervalSeconds: req.IntervalSeconds,
+		RemoteAddr:      remote,
+		LastSeen:        time.Now(),
+	}
+
+	// Upsert: station baru langsung kedaftar, yang lama cuma diupdate
+	err = database.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"name", "hostname", "version", "os", "backends", "devices", "profiles", "config_version",
+			"last_error", "last_error_at", "interval_seconds", "remote_addr", "last_seen", "updated_at",
+		}),
+	}).Create(&station).Error
+	if err != nil {
+		log.Println("Gagal simpan heartbeat station:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Gagal menyimpan data station"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+}
+
+// stationsHandler: GET /stations, station dianggap offline kalau udah
+// lewat 3x interval heartbeat-nya gak lapor
+func stationsHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	var stations []database.Station
+	if err := database.DB.Order("name ASC, id ASC").Find(&stations).Error; err != nil {
+		log.Println("Error fetching stations:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Gagal ambil data station"})
+		return
+	}
+
+	var latest uint
+	database.DB.Model(&database.ProfileConfig{}).Select("COALESCE(MAX(version), 0)").Scan(&latest)
+
+	now := time.Now()
+	data := make([]dbclient.Station, 0, len(stations))
+	for _, s := range stations {
+		interval := time.Duration(s.IntervalSeconds) * time.Second
+		if interval <= 0 {
+			interval = defaultHeartbeatSeconds * time.Second
+		}
+		data = append(data, dbclient.Station{
+			ID:              s.ID,
+			Name:            s.Name,
+			Hostname:        s.Hostname,
+			Version:         s.Version,
+			OS:              s.OS,
+			Backends:        s.Backends,
+			Devices:         s.Devices,
+			Profiles:        s.Profiles,
+			ConfigVersion:   s.ConfigVersion,
+			LastError:       s.LastError,
+			LastErrorAt:     s.LastErrorAt,
+			IntervalSeconds: s.IntervalSeconds,
+			RemoteAddr:      s.RemoteAddr,
+			LastSeen:        s.LastSeen,
+			CreatedAt:       s.CreatedAt,
+			UpdatedAt:       s.UpdatedAt,
+			Online:          now.Sub(s.LastSeen) < 3*interval,
+			ConfigOutdated:  uint(s.ConfigVersion) < latest,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    data,
+	})
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	root := filepath.Join(t.TempDir(), "scans")
+	l, err := NewLocal(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := l.Exists(ctx, "123_A.pdf"); ok || err != nil {
+		t.Fatalf("Exists sebelum Put = %v, %v", ok, err)
+	}
+	if _, err := l.Open(ctx, "123_A.pdf"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open key yang gak ada: err = %v, harusnya ErrNotFound", err)
+	}
+
+	if err := l.Put(ctx, "123_A.pdf", strings.NewReader("%PDF-A"), 6); err != nil {
+		t.Fatal(err)
+	}
+	obj, err := l.Open(ctx, "123_A.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(obj)
+	obj.Close()
+	if string(data) != "%PDF-A" || obj.Size != 6 {
+		t.Fatalf("isi %q, size %d", data, obj.Size)
+	}
+
+	// PutFile mindahin file, src-nya hilang
+	src := filepath.Join(t.TempDir(), "staging.pdf")
+	os.WriteFile(src, []byte("%PDF-B"), 0644)
+	if err := l.PutFile(ctx, "123_B.pdf", src); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(src); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("file staging masih ada setelah PutFile: %v", err)
+	}
+	if ok, _ := l.Exists(ctx, "123_B.pdf"); !ok {
+		t.Error("123_B.pdf gak ada setelah PutFile")
+	}
+
+	// File sementara (diawali titik) & folder gak ikut List
+	os.WriteFile(filepath.Join(root, ".put-123"), nil, 0644)
+	os.Mkdir(filepath.Join(root, "123_dir"), 0755)
+	l.Put(ctx, "456_C.pdf", strings.NewReader("x"), 1)
+	keys, err := l.List(ctx, "123_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(keys)
+	if want := []string{"123_A.pdf", "123_B.pdf"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("List = %v, harusnya %v", keys, want)
+	}
+
+	if err := l.Delete(ctx, "123_A.pdf"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Delete(ctx, "123_A.pdf"); err != nil {
+		t.Errorf("Delete key yang udah gak ada harusnya sukses: %v", err)
+	}
+	if ok, _ := l.Exists(ctx, "123_A.pdf"); ok {
+		t.Error("123_A.pdf masih ada setelah Delete")
+	}
+}
+
+func TestLocalRejectsUnsafeKey(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	l, err := NewLocal(filepath.Join(dir, "scans"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"../keluar.pdf", "a/b.pdf", ".hidden"} {
+		if err := l.Put(ctx, key, strings.NewReader("x"), 1); err == nil {
+			t.Errorf("Put(%q) harusnya ditolak", key)
+		}
+		if _, err := l.Exists(ctx, key); err == nil {
+			t.Errorf("Exists(%q) harusnya ditolak", key)
+		}
+		if err := l.Delete(ctx, key); err == nil {
+			t.Errorf("Delete(%q) harusnya ditolak", key)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "keluar.pdf")); err == nil {
+		t.Error("file ketulis di luar root storage")
+	}
+}
@@ -0,0 +1,90 @@
+// Package storage: tempat nyimpen PDF hasil scan, bisa folder lokal atau
+// object storage S3-compatible (MinIO, AWS S3, dll). Dipilih lewat env
+// STORAGE_BACKEND (local / s3).
+//
+// Key = nama file datar (NPSN_SNBAPP.pdf), gak ada folder. Kolom
+// scan_records.path isinya Location(key) dari backend yang nyimpen.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type Storage interface {
+	// Name: local / s3, buat log
+	Name() string
+	// Location: path / URL yang disimpen di scan_records.path
+	Location(key string) string
+	Exists(ctx context.Context, key string) (bool, error)
+	// Open: ErrNotFound kalau key gak ada. Object wajib di-Close.
+	Open(ctx context.Context, key string) (*Object, error)
+	// Put simpan isi r (size byte). Object gak pernah kelihatan setengah jadi.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// PutFile mindahin file lokal src ke storage, kalau sukses src udah gak ada
+	PutFile(ctx context.Context, key, src string) error
+	// Delete: key yang gak ada dianggap sukses
+	Delete(ctx context.Context, key string) error
+	// List semua key yang diawali prefix
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+type Object struct {
+	io.ReadCloser
+	Size    int64
+	ModTime time.Time
+}
+
+var ErrNotFound = errors.New("file tidak ditemukan di storage")
+
+var keyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// CheckKey: key harus nama file datar, gak bisa keluar dari root / bucket
+func CheckKey(key string) error {
+	if len(key) > 200 || !keyPattern.MatchString(key) || strings.Contains(key, "..") {
+		return fmt.Errorf("key storage %q tidak aman", key)
+	}
+	return nil
+}
+
+// KeyOf ambil key dari scan_records.path (path lokal lama atau Location backend mana pun)
+func KeyOf(location string) string {
+	return path.Base(filepath.ToSlash(location))
+}
+
+// FromEnv buka backend sesuai STORAGE_BACKEND (default local)
+func FromEnv() (Storage, error) {
+	return Open(os.Getenv("STORAGE_BACKEND"))
+}
+
+// Open buka backend dengan setting dari env:
+//
+//	local: SCAN_STORAGE_PATH (default ./scans)
+//	s3:    S3_ENDPOINT, S3_REGION, S3_BUCKET, S3_ACCESS_KEY, S3_SECRET_KEY,
+//	       S3_PREFIX (opsional), S3_PATH_STYLE (default true, buat MinIO)
+func Open(backend string) (Storage, error) {
+	switch backend {
+	case "", "local":
+		return NewLocal(LocalPath())
+	case "s3":
+		return NewS3(S3ConfigFromEnv())
+	}
+	return nil, fmt.Errorf("STORAGE_BACKEND %q tidak dikenal, pilih local atau s3", backend)
+}
+
+// LocalPath: folder storage lokal dari SCAN_STORAGE_PATH
+func LocalPath() string {
+	storageDir := os.Getenv("SCAN_STORAGE_PATH")
+	if storageDir == "" {
+		storageDir = "./scans" // Default Linux friendly
+	}
+	return storageDir
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckKey(t *testing.T) {
+	tests := []struct {
+		key string
+		ok  bool
+	}{
+		{"20100001_BAPP-01.pdf", true},
+		{"20100001_BAPP-01_v2.pdf", true},
+		{strings.Repeat("a", 200), true},
+		{strings.Repeat("a", 201), false},
+		{"", false},
+		{"../20100001_BAPP.pdf", false},
+		{"..", false},
+		{"a..pdf", false},
+		{"a/b.pdf", false},
+		{`a\b.pdf`, false},
+		{".hidden.pdf", false},
+		{"-flag.pdf", false},
+		{"spasi di nama.pdf", false},
+	}
+	for _, tt := range tests {
+		if err := CheckKey(tt.key); (err == nil) != tt.ok {
+			t.Errorf("CheckKey(%q) = %v, harusnya lolos %v", tt.key, err, tt.ok)
+		}
+	}
+}
+
+func TestKeyOf(t *testing.T) {
+	for location, want := range map[string]string{
+		"scans/123_ABC.pdf":                 "123_ABC.pdf",
+		"s3://bucket/scans/123_ABC_v2.pdf":  "123_ABC_v2.pdf",
+		"/var/lib/owo/scans/123_ABC_v3.pdf": "123_ABC_v3.pdf",
+	} {
+		if got := KeyOf(location); got != want {
+			t.Errorf("KeyOf(%q) = %q, harusnya %q", location, got, want)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"dbclient"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// POST /save/upload: sama kayak /save tapi multipart/form-data, gambar
+// dikirim mentah (tanpa base64).
+//
+//	Field: mode, doc_name, npsn, sn_bapp, hasil_cek, kode, submission_id
+//	File:  page_1, page_2, ... (urutan PDF), opsional field page_N_side,
+//	       page_N_sheet, page_N_label, page_N_meta (JSON PageMeta)
+//	       atau format lama: image_front & image_back (+ image_front_meta, image_back_meta)
+//
+// Semua part dicek dulu, yang gak valid dibalikin satu-satu di field errors.
+
+const maxFieldBytes = 64 << 10
+
+func uploadHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w)
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	release, ok := acquireSave(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxSaveBytes)
+	fields, files, errs, err := readUploadParts(r)
+	if err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": fmt.Sprintf("Upload lebih dari %d MB", maxSaveBytes>>20)})
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Request multipart tidak valid: " + err.Error()})
+		return
+	}
+
+	req := dbclient.SaveRequest{
+		Mode:     fields["mode"],
+		DocName:  fields["doc_name"],
+		NPSN:     fields["npsn"],
+		SNBapp:   fields["sn_bapp"],
+		HasilCek: fields["hasil_cek"],
+		Kode:     fields["kode"],
+
+		SubmissionID: fields["submission_id"],
+	}
+	id, idErrs, err := validateIdentity(req.NPSN, req.SNBapp)
+	if err != nil {
+		log.Println("Error cek data sekolah:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "message": "Gagal cek data sekolah"})
+		return
+	}
+	errs = append(errs, idErrs...)
+	pages, pageErrs := uploadPages(fields, files)
+	errs = append(errs, pageErrs...)
+	if len(errs) > 0 {
+		writePartErrors(w, errs)
+		return
+	}
+	saveDocument(r.Context(), w, req, id, pages)
+}
+
+// readUploadParts baca semua part. Part yang kegedean / namanya asing masuk
+// errs (request tetap dibaca sampai habis biar semua error kelihatan), err
+// cuma buat request yang rusak total.
+func readUploadParts(r *http.Request) (map[string]string, map[string][]byte, []dbclient.PartError, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, nil, nil, errors.New("harus multipart/form-data")
+	}
+
+	fields := make(map[string]string)
+	files := make(map[string][]byte)
+	var errs []dbclient.PartError
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		name := part.FormName()
+
+		if part.FileName() == "" {
+			data, err := io.ReadAll(io.LimitReader(part, maxFieldBytes+1))
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if len(data) > maxFieldBytes {
+				errs = append(errs, dbclient.PartError{Part: name, Message: fmt.Sprintf("field lebih dari %d KB", maxFieldBytes>>10)})
+				continue
+			}
+			fields[name] = strings.TrimSpace(string(data))
+			continue
+		}
+
+		if name != "image_front" && name != "image_back" && pageNumber(name) == 0 {
+			errs = append(errs, dbclient.PartError{Part: name, Message: "nama part tidak dikenal, pakai page_1, page_2, ... atau image_front / image_back"})
+			if _, err := io.Copy(io.Discard, part); err != nil {
+				return nil, nil, nil, err
+			}
+			continue
+		}
+		if _, dup := files[name]; dup {
+			errs = append(errs, dbclient.PartError{Part: name, Message: "part dikirim lebih dari sekali"})
+			if _, err := io.Copy(io.Discard, part); err != nil {
+				return nil, nil, nil, err
+			}
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(part, maxPageBytes+1))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if len(data) > maxPageBytes {
+			if _, err := io.Copy(io.Discard, part); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		// Yang kegedean tetap dicatat biar dicek checkImage (pesan ukuran) & urutan halaman
+		files[name] = data
+	}
+	return fields, files, errs, nil
+}
+
+// pageNumber: page_3 -> 3, selain itu 0
+func pageNumber(name string) int {
+	rest, ok := strings.CutPrefix(name, "page_")
+	if !ok {
+		return 0
+	}
+	n, err := strconv.Atoi(rest)
+	if err != nil || n < 1 || strconv.Itoa(n) != rest {
+		return 0
+	}
+	return n
+}
+
+// uploadPages susun halaman sesuai nomor part & cek tiap gambar
+func uploadPages(fields map[string]string, files map[string][]byte) ([]pageImage, []dbclient.PartError) {
+	var names []string
+	var numbers []int
+	for name := range files {
+		if n := pageNumber(name); n > 0 {
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Ints(numbers)
+
+	_, hasFront := files["image_front"]
+	_, hasBack := files["image_back"]
+	switch {
+	case len(numbers) > 0 && (hasFront || hasBack):
+		return nil, []dbclient.PartError{{Part: "image_front", Message: "jangan campur page_N dengan image_front / image_back"}}
+	case len(numbers) > 0:
+		if len(numbers) > maxPages {
+			return nil, []dbclient.PartError{{Part: "pages", Message: fmt.Sprintf("Maksimal %d halaman per dokumen", maxPages)}}
+		}
+		for i, n := range numbers {
+			if n != i+1 {
+				return nil, []dbclient.PartError{{Part: fmt.Sprintf("page_%d", i+1), Message: "halaman tidak ada, nomor page harus urut mulai page_1 tanpa loncat"}}
+			}
+			names = append(names, fmt.Sprintf("page_%d", n))
+		}
+	case hasFront:
+		names = append(names, "image_front")
+		if hasBack {
+			names = append(names, "image_back")
+		}
+	case hasBack:
+		return nil, []dbclient.PartError{{Part: "image_front", Message: "image_back dikirim tanpa image_front"}}
+	default:
+		return nil, []dbclient.PartError{{Part: "pages", Message: "Dokumen minimal harus punya satu halaman"}}
+	}
+
+	var images []pageImage
+	var errs []dbclient.PartError
+	for _, name := range names {
+		page := dbclient.Page{Side: fields[name+"_side"], Label: fields[name+"_label"]}
+		switch name {
+		case "image_front":
+			page.Sheet, page.Side = 1, dbclient.PageSideFront
+		case "image_back":
+			page.Sheet, page.Side = 1, dbclient.PageSideBack
+		}
+
+		ok := true
+		if v := fields[name+"_sheet"]; v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, dbclient.PartError{Part: name + "_sheet", Message: "sheet harus angka"})
+				ok = false
+			}
+			page.Sheet = n
+		}
+		if v := fields[name+"_meta"]; v != "" {
+			var meta dbclient.PageMeta
+			if err := json.Unmarshal([]byte(v), &meta); err != nil {
+				errs = append(errs, dbclient.PartError{Part: name + "_meta", Message: "meta bukan JSON yang valid"})
+				ok = false
+			}
+			page.Meta = &meta
+		}
+		if err := validatePage(page); err != nil {
+			errs = append(errs, dbclient.PartError{Part: name, Message: err.Error()})
+			ok = false
+		}
+		typ, cfg, err := checkImage(files[name])
+		if err != nil {
+			errs = append(errs, dbclient.PartError{Part: name, Message: err.Error()})
+			continue
+		}
+		img := newPageImage(page, files[name], typ)
+		if err := verifyMeta(page.Meta, img, cfg); ok && err != nil {
+			errs = append(errs, dbclient.PartError{Part: name + "_meta", Message: err.Error()})
+			ok = false
+		}
+		if ok {
+			images = append(images, img)
+		}
+	}
+	return images, errs
+}
@@ -0,0 +1,245 @@
+package dbclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client ke service db. Aman dipakai bareng dari banyak goroutine.
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// New bikin client ke baseURL (mis. http://10.0.0.5:5000). httpClient nil =
+// client dengan timeout 2 menit (cukup buat upload scan yang gede).
+func New(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 2 * time.Minute}
+	}
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), http: httpClient}
+}
+
+// BaseURL yang dipakai client (tanpa / di belakang)
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// envelope: bentuk umum response JSON service db
+type envelope struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+	Errors  []PartError     `json:"errors,omitempty"`
+}
+
+// do kirim request JSON dan balikin response yang statusnya udah dicek 2xx
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Response, error) {
+	if body == nil {
+		return c.doRaw(ctx, method, path, query, "", nil, nil)
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return c.doRaw(ctx, method, path, query, "application/json", bytes.NewReader(data), nil)
+}
+
+// doRaw: kayak do tapi body, content type & header tambahan bebas (mis. multipart)
+func (c *Client) doRaw(ctx context.Context, method, path string, query url.Values, contentType string, body io.Reader, header http.Header) (*http.Response, error) {
+	if c.baseURL == "" {
+		return nil, ErrNoBaseURL
+	}
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for name, values := range header {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+	req.Header.Set("X-DB-Client", "dbclient/"+Version)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var env envelope
+	msg := strings.TrimSpace(string(raw))
+	if json.Unmarshal(raw, &env) == nil && env.Message != "" {
+		msg = env.Message
+	}
+	if msg == "" {
+		msg = http.StatusText(resp.StatusCode)
+	}
+	return nil, &APIError{StatusCode: resp.StatusCode, Message: msg, Parts: env.Errors}
+}
+
+// call: request JSON biasa, field data (kalau out gak nil) di-decode ke out
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, body, out interface{}) (string, error) {
+	resp, err := c.do(ctx, method, path, query, body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var env envelope
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return "", fmt.Errorf("response %s %s gak valid: %v", method, path, err)
+	}
+	if out != nil && len(env.Data) > 0 && string(env.Data) != "null" {
+		if err := json.Unmarshal(env.Data, out); err != nil {
+			return "", fmt.Errorf("data %s %s gak valid: %v", method, path, err)
+		}
+	}
+	return env.Message, nil
+}
+
+// ---------- Dokumen ----------
+
+// Save: POST /save, gabung halaman jadi PDF di server. Dokumen yang udah
+// ada ditolak 409 kecuali req.Mode = SaveModeNewVersion.
+func (c *Client) Save(ctx context.Context, req SaveRequest) error {
+	_, err := c.call(ctx, http.MethodPost, "/save", nil, req, nil)
+	return err
+}
+
+// Records: GET /records, npsn kosong = 50 record terakhir
+func (c *Client) Records(ctx context.Context, npsn string) ([]Record, error) {
+	query := url.Values{}
+	if npsn != "" {
+		query.Set("npsn", npsn)
+	}
+	var records []Record
+	_, err := c.call(ctx, http.MethodGet, "/records", query, nil, &records)
+	return records, err
+}
+
+// History: GET /records/history, semua versi dokumen satu NPSN (terbaru dulu).
+// docKey kosong = semua dokumen NPSN itu.
+func (c *Client) History(ctx context.Context, npsn, docKey string) ([]Record, error) {
+	query := url.Values{"npsn": {npsn}}
+	if docKey != "" {
+		query.Set("doc_key", docKey)
+	}
+	var records []Record
+	_, err := c.call(ctx, http.MethodGet, "/records/history", query, nil, &records)
+	return records, err
+}
+
+// SetCurrent: POST /records/current, jadikan versi lama sebagai versi current lagi
+func (c *Client) SetCurrent(ctx context.Context, id uint) (string, error) {
+	return c.call(ctx, http.MethodPost, "/records/current", nil, SetCurrentRequest{ID: id}, nil)
+}
+
+// Stats: GET /stats, rekap per termin
+func (c *Client) Stats(ctx context.Context) ([]DashboardStat, error) {
+	var stats []DashboardStat
+	_, err := c.call(ctx, http.MethodGet, "/stats", nil, nil, &stats)
+	return stats, err
+}
+
+// IsApproved: GET /is-approved, hasil cek terakhir per NPSN
+func (c *Client) IsApproved(ctx context.Context, q ApprovalQuery) ([]ApprovalResult, error) {
+	query := url.Values{}
+	if q.NoBapp != "" {
+		query.Set("no_bapp", q.NoBapp)
+	}
+	if q.NPSN != "" {
+		query.Set("npsn", q.NPSN)
+	}
+	var results []ApprovalResult
+	_, err := c.call(ctx, http.MethodGet, "/is-approved", query, nil, &results)
+	return results, err
+}
+
+// Delete: POST /delete, hapus semua record & PDF satu NPSN. Balikin pesan server.
+func (c *Client) Delete(ctx context.Context, npsn string) (string, error) {
+	return c.call(ctx, http.MethodPost, "/delete", nil, DeleteRequest{NPSN: npsn}, nil)
+}
+
+// Export: GET /export, file xlsx ditulis ke w
+func (c *Client) Export(ctx context.Context, w io.Writer) error {
+	resp, err := c.do(ctx, http.MethodGet, "/export", nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// ---------- Station ----------
+
+// Heartbeat: POST /stations/heartbeat
+func (c *Client) Heartbeat(ctx context.Context, hb Heartbeat) error {
+	_, err := c.call(ctx, http.MethodPost, "/stations/heartbeat", nil, hb, nil)
+	return err
+}
+
+// Stations: GET /stations
+func (c *Client) Stations(ctx context.Context) ([]Station, error) {
+	var stations []Station
+	_, err := c.call(ctx, http.MethodGet, "/stations", nil, nil, &stations)
+	return stations, err
+}
+
+// ---------- Profile terpusat ----------
+
+// ProfileConfig: GET /profiles/config, version 0 = versi terbaru
+func (c *Client) ProfileConfig(ctx context.Context, version int) (*ProfileConfig, error) {
+	query := url.Values{}
+	if version > 0 {
+		query.Set("version", strconv.Itoa(version))
+	}
+	var cfg ProfileConfig
+	if _, err := c.call(ctx, http.MethodGet, "/profiles/config", query, nil, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// PublishProfiles: POST /profiles/config, balikin nomor versi baru.
+// adminToken = ADMIN_TOKEN service db.
+func (c *Client) PublishProfiles(ctx context.Context, adminToken string, req PublishProfilesRequest) (int, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return 0, err
+	}
+	header := http.Header{"Authorization": {"Bearer " + adminToken}}
+	resp, err := c.doRaw(ctx, http.MethodPost, "/profiles/config", nil, "application/json", bytes.NewReader(data), header)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	var res struct {
+		Version int `json:"version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return 0, fmt.Errorf("response POST /profiles/config gak valid: %v", err)
+	}
+	return res.Version, nil
+}
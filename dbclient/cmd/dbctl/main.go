@@ -0,0 +1,235 @@
+// dbctl: CLI kecil buat service db (cek data, hapus, export, station, profile
+// terpusat) tanpa harus buka dashboard atau curl manual.
+//
+//	dbctl -url http://10.0.0.5:5000 records -npsn 2010
+//	dbctl upload -npsn 20100001 -sn SN123 [-new-version] depan.jpg belakang.jpg
+//	dbctl history -npsn 20100001 [-doc SN123]
+//	dbctl set-current -id 42
+//	dbctl stats
+//	dbctl approved -bapp 123/BAPP/2024
+//	dbctl delete -npsn 20100001
+//	dbctl export -o records.xlsx
+//	dbctl stations
+//	dbctl profiles [-version 3]
+//	dbctl publish -f profiles.json -note "DPI 300 semua" (butuh -token / OWO_DB_ADMIN_TOKEN)
+//
+// URL default diambil dari env OWO_DB_API_URL (sama dengan bridge).
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"dbclient"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: dbctl [-url URL] <upload|records|history|set-current|stats|approved|delete|export|stations|profiles|publish> [flags]")
+	flag.PrintDefaults()
+}
+
+func main() {
+	baseURL := flag.String("url", os.Getenv("OWO_DB_API_URL"), "URL service db (default env OWO_DB_API_URL)")
+	timeout := flag.Duration("timeout", 2*time.Minute, "Timeout per request")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	client := dbclient.New(*baseURL, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if err := run(ctx, client, flag.Arg(0), flag.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, client *dbclient.Client, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	switch cmd {
+	case "records":
+		npsn := fs.String("npsn", "", "Filter NPSN (LIKE)")
+		fs.Parse(args)
+		records, err := client.Records(ctx, *npsn)
+		if err != nil {
+			return err
+		}
+		return printJSON(records)
+
+	case "upload":
+		npsn := fs.String("npsn", "", "NPSN (wajib)")
+		sn := fs.String("sn", "", "SN BAPP")
+		docName := fs.String("doc", "", "Nama dokumen")
+		newVersion := fs.Bool("new-version", false, "Simpan sebagai versi baru kalau dokumen sudah ada")
+		fs.Parse(args)
+		if *npsn == "" || fs.NArg() == 0 {
+			return fmt.Errorf("-npsn dan minimal satu file gambar wajib diisi")
+		}
+		req := dbclient.SaveRequest{NPSN: *npsn, SNBapp: *sn, DocName: *docName}
+		if *newVersion {
+			req.Mode = dbclient.SaveModeNewVersion
+		}
+		var pages []dbclient.UploadPage
+		for _, path := range fs.Args() {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			pages = append(pages, dbclient.UploadPage{FileName: filepath.Base(path), Data: f})
+		}
+		err := client.Upload(ctx, req, pages)
+		var apiErr *dbclient.APIError
+		if errors.As(err, &apiErr) {
+			for _, p := range apiErr.Parts {
+				fmt.Fprintf(os.Stderr, "  %s: %s\n", p.Part, p.Message)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d halaman tersimpan\n", len(pages))
+		return nil
+
+	case "history":
+		npsn := fs.String("npsn", "", "NPSN (wajib)")
+		doc := fs.String("doc", "", "SN BAPP dokumen (kosong = semua dokumen NPSN itu)")
+		fs.Parse(args)
+		if *npsn == "" {
+			return fmt.Errorf("-npsn wajib diisi")
+		}
+		records, err := client.History(ctx, *npsn, *doc)
+		if err != nil {
+			return err
+		}
+		return printJSON(records)
+
+	case "set-current":
+		id := fs.Uint("id", 0, "ID record yang dijadikan versi current (wajib)")
+		fs.Parse(args)
+		if *id == 0 {
+			return fmt.Errorf("-id wajib diisi")
+		}
+		msg, err := client.SetCurrent(ctx, *id)
+		if err != nil {
+			return err
+		}
+		fmt.Println(msg)
+		return nil
+
+	case "stats":
+		fs.Parse(args)
+		stats, err := client.Stats(ctx)
+		if err != nil {
+			return err
+		}
+		return printJSON(stats)
+
+	case "approved":
+		npsn := fs.String("npsn", "", "NPSN")
+		bapp := fs.String("bapp", "", "Nomor BAPP")
+		fs.Parse(args)
+		results, err := client.IsApproved(ctx, dbclient.ApprovalQuery{NoBapp: *bapp, NPSN: *npsn})
+		if err != nil {
+			return err
+		}
+		return printJSON(results)
+
+	case "delete":
+		npsn := fs.String("npsn", "", "NPSN yang dihapus (wajib)")
+		fs.Parse(args)
+		if *npsn == "" {
+			return fmt.Errorf("-npsn wajib diisi")
+		}
+		msg, err := client.Delete(ctx, *npsn)
+		if err != nil {
+			return err
+		}
+		fmt.Println(msg)
+		return nil
+
+	case "export":
+		out := fs.String("o", "export_records.xlsx", "File output (- = stdout)")
+		fs.Parse(args)
+		var w io.Writer = os.Stdout
+		if *out != "-" {
+			f, err := os.Create(*out)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := client.Export(ctx, w); err != nil {
+			return err
+		}
+		if *out != "-" {
+			fmt.Fprintln(os.Stderr, "Export disimpan ke", *out)
+		}
+		return nil
+
+	case "stations":
+		fs.Parse(args)
+		stations, err := client.Stations(ctx)
+		if err != nil {
+			return err
+		}
+		return printJSON(stations)
+
+	case "profiles":
+		version := fs.Int("version", 0, "Versi tertentu (0 = terbaru)")
+		fs.Parse(args)
+		cfg, err := client.ProfileConfig(ctx, *version)
+		if err != nil {
+			return err
+		}
+		return printJSON(cfg)
+
+	case "publish":
+		file := fs.String("f", "", "File JSON berisi {\"profiles\": [...], \"quality\": {...}} (wajib)")
+		note := fs.String("note", "", "Catatan versi")
+		token := fs.String("token", os.Getenv("OWO_DB_ADMIN_TOKEN"), "Token admin service db (default env OWO_DB_ADMIN_TOKEN)")
+		fs.Parse(args)
+		if *file == "" {
+			return fmt.Errorf("-f wajib diisi")
+		}
+		if *token == "" {
+			return fmt.Errorf("-token atau env OWO_DB_ADMIN_TOKEN wajib diisi")
+		}
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			return err
+		}
+		var req dbclient.PublishProfilesRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return fmt.Errorf("%s bukan JSON profile yang valid: %v", *file, err)
+		}
+		if *note != "" {
+			req.Note = *note
+		}
+		version, err := client.PublishProfiles(ctx, *token, req)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Profile terpusat versi %d dipublish\n", version)
+		return nil
+	}
+	return fmt.Errorf("perintah tidak dikenal: %s", cmd)
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,11 @@
+// Package dbclient: client Go buat API service db (save, records, stats,
+// is-approved, delete, export, station & profile terpusat).
+//
+// Semua struct request/response di sini dipakai langsung oleh service db,
+// scanner-bridge, dan CLI dbctl, jadi kontraknya gak bisa beda diam-diam.
+// Ubah field = ubah di sini, lalu naikin Version (dan tag git dbclient/vX.Y.Z).
+// Field baru harus opsional (omitempty) biar bridge versi lama tetap jalan.
+package dbclient
+
+// Version versi kontrak API, dikirim di header X-DB-Client tiap request
+const Version = "1.6.0"
@@ -0,0 +1,49 @@
+package dbclient
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoBaseURL: URL service db belum diset
+var ErrNoBaseURL = errors.New("URL service db belum diset")
+
+// APIError: service db jawab dengan status selain 2xx
+type APIError struct {
+	StatusCode int
+	Message    string      // Field message dari response, atau body mentah
+	Parts      []PartError // Detail per halaman / part kalau save ditolak validasi
+}
+
+// PartError: satu halaman / part upload yang gak valid
+type PartError struct {
+	Part    string `json:"part"` // Nama part multipart (page_2) atau pages[1] buat JSON
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	if e.Rejected() {
+		return fmt.Sprintf("ditolak server (%d): %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("server error (%d): %s", e.StatusCode, e.Message)
+}
+
+// Rejected: request-nya yang salah (4xx), gak ada gunanya dikirim ulang
+func (e *APIError) Rejected() bool {
+	return e.StatusCode >= 400 && e.StatusCode < 500
+}
+
+// IsRejected: err (atau yang dibungkusnya) APIError 4xx
+func IsRejected(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Rejected()
+}
+
+// StatusCode dari APIError di dalam err, 0 kalau bukan APIError (mis. gagal konek)
+func StatusCode(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
@@ -0,0 +1,3 @@
+module dbclient
+
+go 1.25.6
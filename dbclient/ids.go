@@ -0,0 +1,45 @@
+package dbclient
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// NPSN & SN BAPP ikut jadi nama file PDF di server, jadi formatnya dibatasi
+// ketat: gak boleh ada separator path, titik dua, atau underscore (dipakai
+// pemisah di nama file NPSN_SNBAPP_vN.pdf).
+
+// NPSN yang udah lolos ParseNPSN
+type NPSN string
+
+// SNBapp yang udah lolos ParseSNBapp, boleh kosong (dokumen tanpa SN)
+type SNBapp string
+
+var (
+	// 8 karakter, biasanya angka semua; satuan pendidikan non-formal ada yang diawali huruf
+	npsnPattern   = regexp.MustCompile(`^[0-9A-Z]{8}$`)
+	snBappPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.-]{0,63}$`)
+)
+
+func ParseNPSN(s string) (NPSN, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", fmt.Errorf("NPSN wajib diisi")
+	}
+	if !npsnPattern.MatchString(s) {
+		return "", fmt.Errorf("NPSN %q tidak valid, harus 8 karakter angka / huruf besar", s)
+	}
+	return NPSN(s), nil
+}
+
+func ParseSNBapp(s string) (SNBapp, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+	if !snBappPattern.MatchString(s) || strings.Contains(s, "..") {
+		return "", fmt.Errorf("SN BAPP %q tidak valid, cuma boleh huruf, angka, titik, dan strip (maksimal 64 karakter)", s)
+	}
+	return SNBapp(s), nil
+}
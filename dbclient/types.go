@@ -0,0 +1,232 @@
+package dbclient
+
+import "time"
+
+// ---------- Dokumen ----------
+
+// Mode simpan POST /save
+const (
+	SaveModeNew        = ""            // Default: ditolak 409 kalau dokumen udah pernah disimpan
+	SaveModeNewVersion = "new_version" // Simpan sebagai versi baru, versi lama tetap ada
+)
+
+// SaveRequest: body POST /save. Halaman dikirim lewat Pages (urut sesuai
+// PDF); format lama ImageFront / ImageBack (satu lembar) masih diterima
+// kalau Pages kosong.
+type SaveRequest struct {
+	Mode     string `json:"mode,omitempty"` // SaveModeNew / SaveModeNewVersion
+	DocName  string `json:"doc_name"`
+	NPSN     string `json:"npsn"`
+	SNBapp   string `json:"sn_bapp"`
+	HasilCek string `json:"hasil_cek"`
+	Kode     string `json:"kode"`
+	Pages    []Page `json:"pages,omitempty"`
+	// ID unik buatan client per submission (mis. ID outbox bridge). Kirim
+	// ulang dengan ID yang sama gak bikin record dobel, server balikin
+	// record yang udah ada.
+	SubmissionID string `json:"submission_id,omitempty"`
+
+	ImageFront string `json:"image_front,omitempty"` // Data URI / base64 JPEG
+	ImageBack  string `json:"image_back,omitempty"`
+	// Metadata & hash halaman dari bridge
+	FrontMeta *PageMeta `json:"image_front_meta,omitempty"`
+	BackMeta  *PageMeta `json:"image_back_meta,omitempty"`
+}
+
+// Sisi kertas satu halaman
+const (
+	PageSideFront = "front"
+	PageSideBack  = "back"
+)
+
+// Page: satu halaman dokumen di SaveRequest.Pages
+type Page struct {
+	Image string    `json:"image"`           // Data URI / base64 JPEG
+	Sheet int       `json:"sheet,omitempty"` // Nomor lembar, mulai 1
+	Side  string    `json:"side,omitempty"`  // PageSideFront / PageSideBack
+	Label string    `json:"label,omitempty"` // Judul halaman di PDF, kosong = otomatis dari sheet & side
+	Meta  *PageMeta `json:"meta,omitempty"`
+}
+
+// PageList: halaman yang masuk PDF, request format lama diubah jadi
+// halaman depan (& belakang) lembar 1
+func (r SaveRequest) PageList() []Page {
+	if len(r.Pages) > 0 {
+		return r.Pages
+	}
+	var pages []Page
+	if r.ImageFront != "" {
+		pages = append(pages, Page{Image: r.ImageFront, Sheet: 1, Side: PageSideFront, Meta: r.FrontMeta})
+	}
+	if r.ImageBack != "" {
+		pages = append(pages, Page{Image: r.ImageBack, Sheet: 1, Side: PageSideBack, Meta: r.BackMeta})
+	}
+	return pages
+}
+
+// PageMeta: metadata satu halaman hasil scan (diisi bridge)
+type PageMeta struct {
+	SHA256     string    `json:"sha256"`
+	Width      int       `json:"width"`
+	Height     int       `json:"height"`
+	DPI        int       `json:"dpi,omitempty"` // 0 = gak diketahui (mis. foto HP)
+	Bytes      int       `json:"bytes"`
+	Format     string    `json:"format"`
+	CapturedAt time.Time `json:"captured_at"`
+	StationID  string    `json:"station_id"`
+	Profile    string    `json:"profile"`
+}
+
+// PageProvenance: asal-usul satu halaman PDF yang tersimpan. SHA256 & Bytes
+// dihitung service db dari gambar yang masuk PDF, Meta dari bridge (kalau
+// dikirim) dan udah dicek cocok dengan gambarnya waktu simpan.
+type PageProvenance struct {
+	Page   int       `json:"page"` // Nomor halaman di PDF, mulai 1
+	Sheet  int       `json:"sheet,omitempty"`
+	Side   string    `json:"side,omitempty"`
+	SHA256 string    `json:"sha256"`
+	Bytes  int       `json:"bytes"`
+	Meta   *PageMeta `json:"meta,omitempty"`
+}
+
+// DeleteRequest: body POST /delete
+type DeleteRequest struct {
+	NPSN string `json:"npsn"`
+}
+
+// Record: satu baris GET /records (scan_records + data sekolah + log terakhir).
+// Satu dokumen (NPSN + SN BAPP) bisa punya banyak versi, yang tampil di
+// /records cuma versi current, sisanya lewat GET /records/history.
+type Record struct {
+	ID          uint      `json:"id"`
+	NPSN        string    `json:"npsn"`
+	DocKey      string    `json:"doc_key"` // SN BAPP waktu dokumen disimpan
+	Version     int       `json:"version"`
+	IsCurrent   bool      `json:"is_current"`
+	NamaSekolah string    `json:"nama_sekolah"`
+	SNBapp      string    `json:"sn_bapp"`
+	HasilCek    string    `json:"hasil_cek"`
+	Kode        string    `json:"kode"`
+	Path        string    `json:"path"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Hash & metadata tiap halaman, cuma diisi GET /records/history
+	Pages []PageProvenance `json:"pages,omitempty" gorm:"-"`
+}
+
+// SetCurrentRequest: body POST /records/current, jadikan record ID ini versi current
+type SetCurrentRequest struct {
+	ID uint `json:"id"`
+}
+
+// DashboardStat: satu baris GET /stats per termin
+type DashboardStat struct {
+	Termin       string `json:"termin"`
+	TotalSchools int64  `json:"total_schools"`
+	Scanned      int64  `json:"scanned"`
+	LogsAccepted int64  `json:"logs_accepted"`
+}
+
+// ApprovalQuery: parameter GET /is-approved, salah satu wajib diisi
+type ApprovalQuery struct {
+	NoBapp string
+	NPSN   string
+}
+
+// ApprovalResult: satu baris GET /is-approved (dari view v_logs)
+type ApprovalResult struct {
+	HasilCek    string `json:"hasil_cek"`
+	NPSN        string `json:"npsn"`
+	SNBapp      string `json:"sn_bapp" gorm:"column:sn_bapp"`
+	NamaSekolah string `json:"nama_sekolah" gorm:"column:nama_sekolah"`
+	Kode        string `json:"kode" gorm:"column:kode"`
+}
+
+// ---------- Station ----------
+
+type Device struct {
+	Backend string `json:"backend"`           // naps2, escl, simulator
+	Name    string `json:"name"`              // Nama scanner
+	Address string `json:"address,omitempty"` // ID driver NAPS2 / URL eSCL
+}
+
+// Heartbeat: body POST /stations/heartbeat dari bridge
+type Heartbeat struct {
+	StationID       string     `json:"station_id"`
+	Name            string     `json:"name"`
+	Hostname        string     `json:"hostname"`
+	Version         string     `json:"version"`
+	OS              string     `json:"os"`
+	Backends        []string   `json:"backends"`
+	Devices         []Device   `json:"devices"`
+	Profiles        []string   `json:"profiles"`
+	ConfigVersion   int        `json:"config_version"` // Versi profile terpusat yang lagi dipakai
+	LastError       string     `json:"last_error,omitempty"`
+	LastErrorAt     *time.Time `json:"last_error_at,omitempty"`
+	IntervalSeconds int        `json:"interval_seconds"`
+}
+
+// Station: satu baris GET /stations
+type Station struct {
+	ID              string     `json:"id"`
+	Name            string     `json:"name"`
+	Hostname        string     `json:"hostname"`
+	Version         string     `json:"version"`
+	OS              string     `json:"os"`
+	Backends        []string   `json:"backends"`
+	Devices         []Device   `json:"devices"`
+	Profiles        []string   `json:"profiles"`
+	ConfigVersion   int        `json:"config_version"`
+	LastError       string     `json:"last_error"`
+	LastErrorAt     *time.Time `json:"last_error_at"`
+	IntervalSeconds int        `json:"interval_seconds"`
+	RemoteAddr      string     `json:"remote_addr"`
+	LastSeen        time.Time  `json:"last_seen"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	Online          bool       `json:"online"`
+	// Bridge masih pakai profile terpusat versi lama (atau belum sync sama sekali)
+	ConfigOutdated bool `json:"config_outdated"`
+}
+
+// ---------- Profile terpusat ----------
+
+// ManagedProfile: setting yang dipaksa ke profile NAPS2 dengan DisplayName sama.
+// Field kosong / nil = pakai setting lokal station.
+type ManagedProfile struct {
+	Name        string `json:"name"`
+	DPI         int    `json:"dpi,omitempty"`          // 100, 150, 200, 300, ...
+	PaperSource string `json:"paper_source,omitempty"` // Glass, Feeder, Duplex
+	BitDepth    string `json:"bit_depth,omitempty"`    // C24Bit, Grayscale, BlackWhite
+	PageSize    string `json:"page_size,omitempty"`    // A4, Letter, Legal, ...
+	Rotate      *bool  `json:"rotate,omitempty"`       // Rotate 180 hasil scan
+	Deskew      *bool  `json:"deskew,omitempty"`       // Luruskan halaman miring
+}
+
+// QualityThresholds: batas warning cek kualitas hasil scan di bridge
+type QualityThresholds struct {
+	MinBrightness float64 `json:"min_brightness"` // Rata-rata luma 0-255, di bawah ini = terlalu gelap
+	MinContrast   float64 `json:"min_contrast"`   // Standar deviasi luma, di bawah ini = pudar
+	MinSharpness  float64 `json:"min_sharpness"`  // Variance of Laplacian, di bawah ini = buram
+	MaxSkew       float64 `json:"max_skew"`       // Derajat, lebih dari ini = miring
+	MaxEdgeInk    float64 `json:"max_edge_ink"`   // Rasio piksel gelap di pinggir, lebih dari ini = kepotong
+}
+
+// ProfileDefinition: isi satu versi profile terpusat
+type ProfileDefinition struct {
+	Profiles []ManagedProfile   `json:"profiles"`
+	Quality  *QualityThresholds `json:"quality,omitempty"` // Nimpa quality di config.json bridge
+}
+
+// PublishProfilesRequest: body POST /profiles/config
+type PublishProfilesRequest struct {
+	ProfileDefinition
+	Note string `json:"note"`
+}
+
+// ProfileConfig: response GET /profiles/config, Version 0 = belum ada yang dipublish
+type ProfileConfig struct {
+	Version   int        `json:"version"`
+	Note      string     `json:"note,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	ProfileDefinition
+}
@@ -0,0 +1,88 @@
+package dbclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+)
+
+// UploadPage: satu gambar buat Upload, Page.Image diabaikan
+type UploadPage struct {
+	Page
+	FileName string    // Nama file di multipart, kosong = page_N.jpg
+	Data     io.Reader // JPEG / PNG mentah
+}
+
+// Upload: POST /save/upload, sama kayak Save tapi gambar dikirim mentah lewat
+// multipart (lebih hemat dari base64). Halaman di req.Pages / ImageFront
+// diabaikan, pakai pages. Kalau ditolak validasi, detail per halaman ada di
+// APIError.Parts.
+func (c *Client) Upload(ctx context.Context, req SaveRequest, pages []UploadPage) error {
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeUpload(mw, req, pages))
+	}()
+
+	resp, err := c.doRaw(ctx, http.MethodPost, "/save/upload", nil, mw.FormDataContentType(), pr, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func writeUpload(mw *multipart.Writer, req SaveRequest, pages []UploadPage) error {
+	fields := [][2]string{
+		{"mode", req.Mode},
+		{"doc_name", req.DocName},
+		{"npsn", req.NPSN},
+		{"sn_bapp", req.SNBapp},
+		{"hasil_cek", req.HasilCek},
+		{"kode", req.Kode},
+		{"submission_id", req.SubmissionID},
+	}
+	for i, p := range pages {
+		name := "page_" + strconv.Itoa(i+1)
+		fields = append(fields, [2]string{name + "_side", p.Side}, [2]string{name + "_label", p.Label})
+		if p.Sheet > 0 {
+			fields = append(fields, [2]string{name + "_sheet", strconv.Itoa(p.Sheet)})
+		}
+		if p.Meta != nil {
+			meta, err := json.Marshal(p.Meta)
+			if err != nil {
+				return err
+			}
+			fields = append(fields, [2]string{name + "_meta", string(meta)})
+		}
+	}
+	for _, f := range fields {
+		if f[1] == "" {
+			continue
+		}
+		if err := mw.WriteField(f[0], f[1]); err != nil {
+			return err
+		}
+	}
+
+	for i, p := range pages {
+		name := "page_" + strconv.Itoa(i+1)
+		fileName := p.FileName
+		if fileName == "" {
+			fileName = name + ".jpg"
+		}
+		part, err := mw.CreateFormFile(name, fileName)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, p.Data); err != nil {
+			return fmt.Errorf("gagal baca %s: %v", fileName, err)
+		}
+	}
+	return mw.Close()
+}
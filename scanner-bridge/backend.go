@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Backend scanner: sumber halaman buat /scan. Default NAPS2, profile lain
+// bisa diarahkan ke backend lain lewat config.json.
+type scanBackend interface {
+	// Scan nulis halaman hasil scan ke dir dan balikin path-nya sesuai urutan.
+	// ctx dibatalin kalau bridge exit / restart di tengah scan.
+	Scan(ctx context.Context, lg *slog.Logger, dir string) ([]string, error)
+	// Pipeline: opsi post-processing buat hasil backend ini
+	Pipeline() pipelineOptions
+	// Name: jenis backend buat label log & metrics
+	Name() string
+}
+
+// backendFor milih backend berdasarkan nama profile
+func backendFor(profile string) scanBackend {
+	for _, p := range config.ESCL {
+		if p.Name == profile {
+			return &esclBackend{profile: p}
+		}
+	}
+	if p, ok := simulatorProfile(profile); ok {
+		return &simulatorBackend{profile: p}
+	}
+	return &naps2Backend{profile: profile}
+}
+
+type naps2Backend struct {
+	profile string
+}
+
+// Scan jalanin NAPS2 ke folder dir dan balikin path hasil scan sesuai urutan halaman
+func (b *naps2Backend) Scan(ctx context.Context, lg *slog.Logger, dir string) ([]string, error) {
+	// Output pattern: $(nnnn) akan diganti jadi urutan angka 4 digit (0001, 0002...)
+	// biar urutan glob tetap bener walaupun lebih dari 9 halaman
+	outputPath := filepath.Join(dir, "scan_$(nnnn).jpg")
+
+	// naps2.console.exe -o "C:\Temp\...\scan_$(nnnn).jpg" -p "Plustek" --force
+	lg.Info("Scanning dengan NAPS2", "naps2_profile", b.profile)
+	cmd := exec.CommandContext(ctx, naps2Path, "-o", outputPath, "-p", b.profile, "--force")
+
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("Gagal scan: %v", errJobCanceled)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Gagal scan: %v | Output NAPS2: %s", err, string(output))
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "scan_*.jpg"))
+	if err != nil {
+		return nil, fmt.Errorf("Gagal mencari file hasil scan: %v", err)
+	}
+	return files, nil
+}
+
+func (b *naps2Backend) Name() string { return "naps2" }
+
+func (b *naps2Backend) Pipeline() pipelineOptions {
+	// Semua profile perlu rotate kecuali SP-1120
+	return pipelineOptions{
+		Rotate: !strings.Contains(b.profile, "SP-1120"),
+		Duplex: true,
+		DPI:    naps2ProfileDPI(b.profile),
+	}
+}
+
+// naps2ProfileDPI: resolusi profile dari profiles.xml, 0 kalau gak ketemu
+func naps2ProfileDPI(name string) int {
+	profiles, err := readNaps2Profiles()
+	if err != nil {
+		return 0
+	}
+	for _, p := range profiles {
+		if p.DisplayName == name {
+			return p.DPI()
+		}
+	}
+	return 0
+}
+
+// Profile NAPS2 dari profiles.xml (cuma field yang kita pakai)
+type Naps2Profile struct {
+	DisplayName string `xml:"DisplayName"`
+	Resolution  string `xml:"Resolution"`  // Dpi100, Dpi200, Dpi300, ...
+	PaperSource string `xml:"PaperSource"` // Glass, Feeder, Duplex
+	BitDepth    string `xml:"BitDepth"`    // C24Bit, Grayscale, BlackWhite
+	DriverName  string `xml:"DriverName"`  // wia, twain, escl, ...
+	Device      struct {
+		ID   string `xml:"ID"`
+		Name string `xml:"Name"`
+	} `xml:"Device"`
+}
+
+// DPI dari nilai Resolution (Dpi300 -> 300), 0 kalau gak kebaca
+func (p Naps2Profile) DPI() int {
+	dpi, err := strconv.Atoi(strings.TrimPrefix(p.Resolution, "Dpi"))
+	if err != nil {
+		return 0
+	}
+	return dpi
+}
+
+func naps2ProfilesPath() (string, error) {
+	appData, err := os.UserConfigDir() // Usually C:\Users\Username\AppData\Roaming
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(appData, "NAPS2", "profiles.xml"), nil
+}
+
+// readNaps2Profiles baca semua profile dari profiles.xml NAPS2 di AppData
+func readNaps2Profiles() ([]Naps2Profile, error) {
+	profilesPath, err := naps2ProfilesPath()
+	if err != nil {
+		return nil, fmt.Errorf("gagal mendeteksi folder AppData: %v", err)
+	}
+	byteValue, err := os.ReadFile(profilesPath)
+	if err != nil {
+		return nil, fmt.Errorf("gagal membuka file profil NAPS2: %v", err)
+	}
+
+	var data struct {
+		Profiles []Naps2Profile `xml:"ScanProfile"`
+	}
+	if err := xml.Unmarshal(byteValue, &data); err != nil {
+		return nil, fmt.Errorf("gagal memparsing file profil: %v", err)
+	}
+	return data.Profiles, nil
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// Sertifikat HTTPS lokal: bridge bikin CA sendiri sekali (disimpen di
+// AppData), terus CA itu dipakai buat nandatanganin sertifikat localhost.
+// Operator cukup install CA-nya sekali ke Trusted Root biar browser percaya.
+
+const (
+	caValidity   = 10 * 365 * 24 * time.Hour
+	leafValidity = 365 * 24 * time.Hour
+	// Sertifikat localhost dibikin ulang kalau sisa umurnya kurang dari ini
+	leafRenewBefore = 30 * 24 * time.Hour
+)
+
+type certPaths struct {
+	CACert, CAKey, Cert, Key string
+}
+
+func tlsPaths() (certPaths, error) {
+	dir, err := appDir()
+	if err != nil {
+		return certPaths{}, err
+	}
+	dir = filepath.Join(dir, "tls")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return certPaths{}, err
+	}
+	return certPaths{
+		CACert: filepath.Join(dir, "ca.pem"),
+		CAKey:  filepath.Join(dir, "ca-key.pem"),
+		Cert:   filepath.Join(dir, "cert.pem"),
+		Key:    filepath.Join(dir, "key.pem"),
+	}, nil
+}
+
+func writePEM(path, typ string, der []byte, perm os.FileMode) error {
+	return os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), perm)
+}
+
+func readPEM(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s bukan file PEM", path)
+	}
+	return block.Bytes, nil
+}
+
+func randomSerial() *big.Int {
+	serial, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	return serial
+}
+
+// loadOrCreateCA baca CA dari disk, kalau belum ada bikin baru
+func loadOrCreateCA(p certPaths) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	certDER, certErr := readPEM(p.CACert)
+	keyDER, keyErr := readPEM(p.CAKey)
+	if certErr == nil && keyErr == nil {
+		cert, err := x509.ParseCertificate(certDER)
+		if err != nil {
+			return nil, nil, err
+		}
+		key, err := x509.ParseECPrivateKey(keyDER)
+		if err != nil {
+			return nil, nil, err
+		}
+		if cert.PermittedDNSDomainsCritical {
+			return cert, key, nil
+		}
+		// CA versi lama belum dibatasi ke localhost, diganti biar kalau
+		// ca-key.pem bocor gak bisa dipakai bikin sertifikat situs lain
+		slog.Warn("CA lokal belum dibatasi ke localhost, dibuat ulang (install ulang CA ke Trusted Root)")
+	} else if !errors.Is(certErr, os.ErrNotExist) && certErr != nil {
+		return nil, nil, certErr
+	}
+
+	slog.Info("Membuat CA lokal untuk HTTPS")
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	host, _ := os.Hostname()
+	tmpl := &x509.Certificate{
+		SerialNumber:          randomSerial(),
+		Subject:               pkix.Name{CommonName: "OWO Scanner Bridge Local CA " + host, Organization: []string{"OWO Scanner Bridge"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+		// CA cuma boleh nandatanganin localhost / loopback
+		PermittedDNSDomainsCritical: true,
+		PermittedDNSDomains:         []string{"localhost"},
+		PermittedIPRanges: []*net.IPNet{
+			{IP: net.IPv4(127, 0, 0, 0).To4(), Mask: net.CIDRMask(8, 32)},
+			{IP: net.IPv6loopback, Mask: net.CIDRMask(128, 128)},
+		},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := writePEM(p.CAKey, "EC PRIVATE KEY", keyBytes, 0600); err != nil {
+		return nil, nil, err
+	}
+	if err := writePEM(p.CACert, "CERTIFICATE", der, 0644); err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	return cert, key, err
+}
+
+// leafValid ngecek sertifikat localhost masih bisa dipake & ditandatangani CA yang sekarang
+func leafValid(p certPaths, ca *x509.Certificate) bool {
+	der, err := readPEM(p.Cert)
+	if err != nil {
+		return false
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return false
+	}
+	if time.Until(cert.NotAfter) < leafRenewBefore {
+		return false
+	}
+	return cert.CheckSignatureFrom(ca) == nil
+}
+
+func createLeaf(p certPaths, ca *x509.Certificate, caKey *ecdsa.PrivateKey) error {
+	slog.Info("Membuat sertifikat HTTPS localhost")
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: randomSerial(),
+		Subject:      pkix.Name{CommonName: "localhost", Organization: []string{"OWO Scanner Bridge"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(leafValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1"), net.IPv6loopback},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return err
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := writePEM(p.Key, "EC PRIVATE KEY", keyBytes, 0600); err != nil {
+		return err
+	}
+	return writePEM(p.Cert, "CERTIFICATE", der, 0644)
+}
+
+// loadTLSCertificate nyiapin CA + sertifikat localhost, generate kalau belum ada / mau expired
+func loadTLSCertificate() (tls.Certificate, error) {
+	p, err := tlsPaths()
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	ca, caKey, err := loadOrCreateCA(p)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("gagal siapin CA: %v", err)
+	}
+	if !leafValid(p, ca) {
+		if err := createLeaf(p, ca, caKey); err != nil {
+			return tls.Certificate{}, fmt.Errorf("gagal bikin sertifikat: %v", err)
+		}
+	}
+	return tls.LoadX509KeyPair(p.Cert, p.Key)
+}
+
+// caCertHandler: GET /ca.crt buat download CA (public, gak perlu token)
+func caCertHandler(w http.ResponseWriter, r *http.Request) {
+	p, err := tlsPaths()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Gagal mendeteksi folder AppData")
+		return
+	}
+	data, err := os.ReadFile(p.CACert)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Sertifikat CA belum dibuat (HTTPS belum aktif)")
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-x509-ca-cert")
+	w.Header().Set("Content-Disposition", "attachment; filename=owo-scanner-bridge-ca.crt")
+	w.Write(data)
+}
+
+// openCACert nyalin CA ke folder data terus buka pakai certificate viewer
+// Windows, dari situ operator tinggal klik "Install Certificate".
+func openCACert() error {
+	p, err := tlsPaths()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(p.CACert)
+	if err != nil {
+		return fmt.Errorf("sertifikat CA belum dibuat, aktifkan tls di config.json dulu: %v", err)
+	}
+	out := filepath.Join(filepath.Dir(p.CACert), "owo-scanner-bridge-ca.crt")
+	if err := os.WriteFile(out, data, 0644); err != nil {
+		return err
+	}
+	slog.Info("Sertifikat CA diexport", "path", out)
+	return exec.Command("cmd", "/c", "start", "", out).Start()
+}
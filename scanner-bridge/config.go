@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Config bridge disimpen di %AppData%\OWO Scanner Bridge\config.json.
+// Kalau file belum ada, dibikinin pakai nilai default di bawah.
+type Config struct {
+	// ID station ini, dicatat di metadata tiap halaman dan dipakai buat
+	// registrasi ke service db. Kalau kosong digenerate sekali lalu disimpen.
+	StationID string `json:"station_id"`
+	// Nama station yang muncul di dashboard. Kosong = hostname PC.
+	StationName string `json:"station_name"`
+	// Interval heartbeat ke service db (detik), 0 = default
+	HeartbeatSeconds int `json:"heartbeat_seconds"`
+	// Interval tarik profile terpusat dari service db (menit), 0 = default
+	ProfileSyncMinutes int `json:"profile_sync_minutes"`
+	// URL database API (service db), contoh: http://10.0.0.5:5000
+	DBAPIURL string `json:"db_api_url"`
+	// Origin frontend yang boleh akses bridge (harus persis, termasuk port)
+	AllowedOrigins []string `json:"allowed_origins"`
+	// Aktifin HTTPS (sertifikat localhost digenerate otomatis) di TLSPort
+	TLS     bool   `json:"tls"`
+	TLSPort string `json:"tls_port"`
+	// Batas warning cek kualitas hasil scan
+	Quality QualityThresholds `json:"quality"`
+	// Batas halaman & ukuran per session biar response gak ngabisin RAM
+	Limits ResponseLimits `json:"limits"`
+	// Level & rotasi file log
+	Log LogConfig `json:"log"`
+	// Mode hot folder buat MFP yang cuma bisa scan-to-folder
+	HotFolder HotFolderConfig `json:"hot_folder"`
+	// Profile scanner jaringan eSCL (AirScan), dipilih lewat nama profile
+	ESCL []ESCLProfile `json:"escl"`
+	// Scanner palsu buat development / test, tiap profile pakai file skenario
+	Simulator []SimulatorProfile `json:"simulator"`
+}
+
+var config = Config{
+	AllowedOrigins: []string{"http://localhost:3000"},
+	TLSPort:        ":5443",
+	Quality:        defaultQualityThresholds,
+	Limits:         defaultResponseLimits,
+	Log:            defaultLogConfig,
+	HotFolder:      defaultHotFolderConfig,
+}
+
+// appDir: folder data bridge di AppData (config, outbox, pairing)
+func appDir() (string, error) {
+	appData, err := os.UserConfigDir() // Usually C:\Users\Username\AppData\Roaming
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(appData, "OWO Scanner Bridge")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+func loadConfig() error {
+	dir, err := appDir()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, "config.json")
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		slog.Info("Config belum ada, bikin default", "path", path)
+		if err := saveConfig(); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(data, &config); err != nil {
+			return fmt.Errorf("config.json rusak: %v", err)
+		}
+	}
+
+	// Station ID harus tetap walau hostname PC diganti, jadi disimpen di config
+	if config.StationID == "" {
+		config.StationID = newStationID()
+		slog.Info("Station ID baru", "station", config.StationID)
+		if err := saveConfig(); err != nil {
+			return err
+		}
+	}
+
+	// Env variable menang kalau diset (enak buat testing)
+	if v := os.Getenv("OWO_DB_API_URL"); v != "" {
+		config.DBAPIURL = v
+	}
+	return nil
+}
+
+func saveConfig() error {
+	dir, err := appDir()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "config.json"), data, 0644)
+}
+
+// newStationID: hostname + suffix acak, biar tetap kebaca di dashboard tapi
+// gak bentrok kalau dua PC namanya sama (mis. sama-sama "ADMIN-PC")
+func newStationID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "station"
+	}
+	return strings.ToLower(host) + "-" + randomHex(3)
+}
+
+func stationID() string {
+	if config.StationID != "" {
+		return config.StationID
+	}
+	if host, err := os.Hostname(); err == nil {
+		return host
+	}
+	return "unknown"
+}
+
+func stationName() string {
+	if config.StationName != "" {
+		return config.StationName
+	}
+	if host, err := os.Hostname(); err == nil {
+		return host
+	}
+	return stationID()
+}
+
+func originAllowed(origin string) bool {
+	for _, o := range config.AllowedOrigins {
+		if strings.EqualFold(strings.TrimRight(o, "/"), origin) {
+			return true
+		}
+	}
+	return bridgeOrigin(origin)
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Backend eSCL (AirScan): scanner jaringan yang ngomong eSCL lewat HTTP,
+// jadi gak perlu NAPS2 atau driver. Alurnya: baca ScannerCapabilities,
+// POST ScanJobs, terus tarik NextDocument sampai 404.
+
+// Profile eSCL di config.json. Kalau Naps2Profile diisi, resolusi / sumber
+// kertas / mode warna diambil dari profile NAPS2 dengan nama itu.
+type ESCLProfile struct {
+	Name         string `json:"name"` // Nama yang muncul di /profiles
+	URL          string `json:"url"`  // Base URL eSCL, mis. http://192.168.1.50/eSCL
+	Naps2Profile string `json:"naps2_profile,omitempty"`
+	Resolution   int    `json:"resolution"` // DPI
+	Source       string `json:"source"`     // Platen atau Feeder
+	Duplex       bool   `json:"duplex"`
+	ColorMode    string `json:"color_mode"` // RGB24, Grayscale8, BlackAndWhite1
+	Rotate       bool   `json:"rotate"`
+}
+
+const (
+	esclSourcePlaten = "Platen"
+	esclSourceFeeder = "Feeder"
+
+	// A4 dalam satuan 1/300 inch
+	esclA4Width  = 2480
+	esclA4Height = 3508
+
+	esclBusyRetries = 30
+)
+
+var (
+	esclClient = &http.Client{Timeout: 2 * time.Minute}
+	// Jeda sebelum nanya NextDocument lagi waktu scanner masih sibuk
+	esclBusyDelay = time.Second
+)
+
+// Struktur ScannerCapabilities (namespace diabaikan, cuma local name)
+type esclSettingProfile struct {
+	ColorModes  []string `xml:"ColorModes>ColorMode"`
+	Resolutions []int    `xml:"SupportedResolutions>DiscreteResolutions>DiscreteResolution>XResolution"`
+}
+
+type esclInputCaps struct {
+	MaxWidth        int                  `xml:"MaxWidth"`
+	MaxHeight       int                  `xml:"MaxHeight"`
+	SettingProfiles []esclSettingProfile `xml:"SettingProfiles>SettingProfile"`
+}
+
+type ESCLCapabilities struct {
+	Version      string         `xml:"Version"`
+	MakeAndModel string         `xml:"MakeAndModel"`
+	Platen       *esclInputCaps `xml:"Platen>PlatenInputCaps"`
+	AdfSimplex   *esclInputCaps `xml:"Adf>AdfSimplexInputCaps"`
+	AdfDuplex    *esclInputCaps `xml:"Adf>AdfDuplexInputCaps"`
+	AdfOptions   []string       `xml:"Adf>AdfOptions>AdfOption"`
+}
+
+func (c *ESCLCapabilities) input(source string, duplex bool) *esclInputCaps {
+	switch {
+	case source == esclSourcePlaten:
+		return c.Platen
+	case duplex && c.AdfDuplex != nil && len(c.AdfDuplex.SettingProfiles) > 0:
+		return c.AdfDuplex
+	default:
+		// Banyak scanner cuma ngisi AdfSimplexInputCaps walaupun bisa duplex
+		return c.AdfSimplex
+	}
+}
+
+func (c *ESCLCapabilities) supportsDuplex() bool {
+	if c.AdfDuplex != nil {
+		return true
+	}
+	for _, o := range c.AdfOptions {
+		if o == "Duplex" {
+			return true
+		}
+	}
+	return false
+}
+
+func fetchESCLCapabilities(base string) (*ESCLCapabilities, error) {
+	resp, err := esclClient.Get(base + "/ScannerCapabilities")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ScannerCapabilities status %d", resp.StatusCode)
+	}
+
+	var caps ESCLCapabilities
+	if err := xml.NewDecoder(resp.Body).Decode(&caps); err != nil {
+		return nil, fmt.Errorf("gagal parsing ScannerCapabilities: %v", err)
+	}
+	return &caps, nil
+}
+
+// esclSettings: hasil mapping profile ke parameter ScanJob
+type esclSettings struct {
+	Source     string
+	Duplex     bool
+	ColorMode  string
+	Resolution int
+	Width      int
+	Height     int
+}
+
+// resolve gabungin profile eSCL dengan profile NAPS2 (kalau direferensiin)
+func (p ESCLProfile) resolve() (ESCLProfile, error) {
+	if p.Naps2Profile == "" {
+		return p, nil
+	}
+	profiles, err := readNaps2Profiles()
+	if err != nil {
+		return p, err
+	}
+	for _, np := range profiles {
+		if np.DisplayName != p.Naps2Profile {
+			continue
+		}
+		if dpi := np.DPI(); dpi > 0 {
+			p.Resolution = dpi
+		}
+		switch np.PaperSource {
+		case "Glass":
+			p.Source, p.Duplex = esclSourcePlaten, false
+		case "Feeder":
+			p.Source, p.Duplex = esclSourceFeeder, false
+		case "Duplex":
+			p.Source, p.Duplex = esclSourceFeeder, true
+		}
+		switch np.BitDepth {
+		case "C24Bit":
+			p.ColorMode = "RGB24"
+		case "Grayscale":
+			p.ColorMode = "Grayscale8"
+		case "BlackWhite":
+			p.ColorMode = "BlackAndWhite1"
+		}
+		return p, nil
+	}
+	return p, fmt.Errorf("profile NAPS2 %q tidak ditemukan", p.Naps2Profile)
+}
+
+// mapESCLSettings nyocokin profile ke kemampuan scanner
+func mapESCLSettings(p ESCLProfile, caps *ESCLCapabilities) (esclSettings, error) {
+	s := esclSettings{
+		Source:     p.Source,
+		Duplex:     p.Duplex,
+		ColorMode:  p.ColorMode,
+		Resolution: p.Resolution,
+		Width:      esclA4Width,
+		Height:     esclA4Height,
+	}
+	if s.Source == "" {
+		s.Source = esclSourceFeeder
+	}
+	if s.Source != esclSourcePlaten && s.Source != esclSourceFeeder {
+		return s, fmt.Errorf("source %q tidak dikenal (Platen/Feeder)", s.Source)
+	}
+	if s.ColorMode == "" {
+		s.ColorMode = "RGB24"
+	}
+	if s.Resolution <= 0 {
+		s.Resolution = 200
+	}
+
+	if s.Duplex && (s.Source != esclSourceFeeder || !caps.supportsDuplex()) {
+		return s, fmt.Errorf("scanner tidak mendukung duplex untuk source %s", s.Source)
+	}
+	in := caps.input(s.Source, s.Duplex)
+	if in == nil {
+		return s, fmt.Errorf("scanner tidak punya source %s", s.Source)
+	}
+
+	if in.MaxWidth > 0 && s.Width > in.MaxWidth {
+		s.Width = in.MaxWidth
+	}
+	if in.MaxHeight > 0 && s.Height > in.MaxHeight {
+		s.Height = in.MaxHeight
+	}
+
+	var modes []string
+	var resolutions []int
+	for _, sp := range in.SettingProfiles {
+		modes = append(modes, sp.ColorModes...)
+		resolutions = append(resolutions, sp.Resolutions...)
+	}
+	if len(modes) > 0 {
+		found := false
+		for _, m := range modes {
+			if m == s.ColorMode {
+				found = true
+			}
+		}
+		if !found {
+			return s, fmt.Errorf("color mode %s tidak didukung (ada: %s)", s.ColorMode, strings.Join(modes, ", "))
+		}
+	}
+	// Pakai resolusi terdekat yang didukung scanner
+	if len(resolutions) > 0 {
+		best := resolutions[0]
+		for _, r := range resolutions {
+			if abs(r-s.Resolution) < abs(best-s.Resolution) {
+				best = r
+			}
+		}
+		s.Resolution = best
+	}
+	return s, nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func (s esclSettings) xml() []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<scan:ScanSettings xmlns:scan="http://schemas.hp.com/imaging/escl/2011/05/03" xmlns:pwg="http://www.pwg.org/schemas/2010/12/sm">` + "\n")
+	b.WriteString("  <pwg:Version>2.6</pwg:Version>\n")
+	b.WriteString("  <pwg:ScanRegions>\n    <pwg:ScanRegion>\n")
+	b.WriteString("      <pwg:ContentRegionUnits>escl:ThreeHundredthsOfInches</pwg:ContentRegionUnits>\n")
+	fmt.Fprintf(&b, "      <pwg:XOffset>0</pwg:XOffset>\n      <pwg:YOffset>0</pwg:YOffset>\n")
+	fmt.Fprintf(&b, "      <pwg:Width>%d</pwg:Width>\n      <pwg:Height>%d</pwg:Height>\n", s.Width, s.Height)
+	b.WriteString("    </pwg:ScanRegion>\n  </pwg:ScanRegions>\n")
+	fmt.Fprintf(&b, "  <pwg:InputSource>%s</pwg:InputSource>\n", s.Source)
+	if s.Source == esclSourceFeeder {
+		fmt.Fprintf(&b, "  <scan:Duplex>%t</scan:Duplex>\n", s.Duplex)
+	}
+	fmt.Fprintf(&b, "  <scan:ColorMode>%s</scan:ColorMode>\n", s.ColorMode)
+	fmt.Fprintf(&b, "  <scan:XResolution>%d</scan:XResolution>\n  <scan:YResolution>%d</scan:YResolution>\n", s.Resolution, s.Resolution)
+	b.WriteString("  <pwg:DocumentFormat>image/jpeg</pwg:DocumentFormat>\n")
+	b.WriteString("  <scan:DocumentFormatExt>image/jpeg</scan:DocumentFormatExt>\n")
+	b.WriteString("</scan:ScanSettings>\n")
+	return b.Bytes()
+}
+
+type esclBackend struct {
+	profile ESCLProfile
+	dpi     int // Resolusi yang akhirnya dipakai scanner
+}
+
+func (b *esclBackend) Name() string { return "escl" }
+
+func (b *esclBackend) Pipeline() pipelineOptions {
+	return pipelineOptions{Rotate: b.profile.Rotate, Duplex: b.profile.Duplex, DPI: b.dpi}
+}
+
+func (b *esclBackend) Scan(ctx context.Context, lg *slog.Logger, dir string) ([]string, error) {
+	p, err := b.profile.resolve()
+	if err != nil {
+		return nil, err
+	}
+	b.profile = p
+	base := strings.TrimRight(p.URL, "/")
+
+	caps, err := fetchESCLCapabilities(base)
+	if err != nil {
+		return nil, fmt.Errorf("Gagal baca kemampuan scanner eSCL: %v", err)
+	}
+	settings, err := mapESCLSettings(p, caps)
+	if err != nil {
+		return nil, fmt.Errorf("Profile %s tidak cocok dengan scanner %s: %v", p.Name, caps.MakeAndModel, err)
+	}
+	b.dpi = settings.Resolution
+	lg.Info("Scanning eSCL", "scanner", caps.MakeAndModel, "url", base, "source", settings.Source, "dpi", settings.Resolution, "duplex", settings.Duplex)
+
+	jobURL, err := b.createJob(ctx, base, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := b.fetchDocuments(ctx, jobURL, dir)
+	if err != nil {
+		// Batalin job biar scanner gak nyangkut di status busy
+		req, _ := http.NewRequest(http.MethodDelete, jobURL, nil)
+		if resp, delErr := esclClient.Do(req); delErr == nil {
+			resp.Body.Close()
+		}
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, nil
+	}
+
+	// Scanner bisa aja ngasih PNG/PDF walau diminta JPEG, samain dulu
+	normDir := filepath.Join(dir, "normalized")
+	if err := os.MkdirAll(normDir, 0755); err != nil {
+		return nil, err
+	}
+	return normalizeFiles(docs, normDir)
+}
+
+func (b *esclBackend) createJob(ctx context.Context, base string, s esclSettings) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/ScanJobs", bytes.NewReader(s.xml()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "text/xml")
+	resp, err := esclClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Gagal kirim ScanJob: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusServiceUnavailable {
+		return "", fmt.Errorf("Scanner eSCL sedang sibuk")
+	}
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("ScanJob ditolak scanner (status %d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	loc := resp.Header.Get("Location")
+	if loc == "" {
+		return "", fmt.Errorf("Scanner tidak mengembalikan lokasi job")
+	}
+	// Location bisa relatif (mis. /eSCL/ScanJobs/123)
+	baseURL, err := url.Parse(base + "/")
+	if err != nil {
+		return "", err
+	}
+	jobURL, err := baseURL.Parse(loc)
+	if err != nil {
+		return "", fmt.Errorf("Lokasi job tidak valid: %v", err)
+	}
+	return strings.TrimRight(jobURL.String(), "/"), nil
+}
+
+// fetchDocuments narik NextDocument sampai scanner bilang habis (404)
+func (b *esclBackend) fetchDocuments(ctx context.Context, jobURL, dir string) ([]string, error) {
+	var docs []string
+	busy := 0
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, jobURL+"/NextDocument", nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := esclClient.Do(req)
+		if ctx.Err() != nil {
+			if err == nil {
+				resp.Body.Close()
+			}
+			return nil, fmt.Errorf("Gagal ambil halaman: %v", errJobCanceled)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Gagal ambil halaman: %v", err)
+		}
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			ext := ".jpg"
+			if mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mt == "application/pdf" {
+				ext = ".pdf"
+			} else if mt == "image/png" {
+				ext = ".png"
+			}
+			path := filepath.Join(dir, fmt.Sprintf("escl_%04d%s", len(docs)+1, ext))
+			f, err := os.Create(path)
+			if err == nil {
+				_, err = io.Copy(f, resp.Body)
+				f.Close()
+			}
+			resp.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("Gagal simpan halaman: %v", err)
+			}
+			docs = append(docs, path)
+			busy = 0
+		case http.StatusNotFound:
+			resp.Body.Close()
+			return docs, nil
+		case http.StatusServiceUnavailable:
+			// Halaman berikutnya belum siap
+			resp.Body.Close()
+			busy++
+			if busy > esclBusyRetries {
+				return nil, fmt.Errorf("Scanner eSCL terlalu lama sibuk")
+			}
+			if err := sleepCtx(ctx, esclBusyDelay); err != nil {
+				return nil, fmt.Errorf("Gagal ambil halaman: %v", err)
+			}
+		default:
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			resp.Body.Close()
+			return nil, fmt.Errorf("NextDocument gagal (status %d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const esclTestCaps = `<?xml version="1.0" encoding="UTF-8"?>
+<scan:ScannerCapabilities xmlns:scan="http://schemas.hp.com/imaging/escl/2011/05/03" xmlns:pwg="http://www.pwg.org/schemas/2010/12/sm">
+  <pwg:Version>2.63</pwg:Version>
+  <pwg:MakeAndModel>Stand-in MFP</pwg:MakeAndModel>
+  <scan:Adf>
+    <scan:AdfSimplexInputCaps>
+      <scan:MaxWidth>2550</scan:MaxWidth>
+      <scan:MaxHeight>4200</scan:MaxHeight>
+      <scan:SettingProfiles>
+        <scan:SettingProfile>
+          <scan:ColorModes>
+            <scan:ColorMode>RGB24</scan:ColorMode>
+            <scan:ColorMode>Grayscale8</scan:ColorMode>
+          </scan:ColorModes>
+          <scan:SupportedResolutions>
+            <scan:DiscreteResolutions>
+              <scan:DiscreteResolution><scan:XResolution>150</scan:XResolution><scan:YResolution>150</scan:YResolution></scan:DiscreteResolution>
+              <scan:DiscreteResolution><scan:XResolution>300</scan:XResolution><scan:YResolution>300</scan:YResolution></scan:DiscreteResolution>
+            </scan:DiscreteResolutions>
+          </scan:SupportedResolutions>
+        </scan:SettingProfile>
+      </scan:SettingProfiles>
+    </scan:AdfSimplexInputCaps>
+    <scan:AdfOptions><scan:AdfOption>Duplex</scan:AdfOption></scan:AdfOptions>
+  </scan:Adf>
+</scan:ScannerCapabilities>`
+
+// esclStandIn: scanner eSCL palsu buat test, satu job per server
+type esclStandIn struct {
+	pages     int // Jumlah halaman sebelum NextDocument 404
+	busy      int // Berapa kali NextDocument balikin 503 sebelum halaman pertama
+	jobStatus int // Status POST ScanJobs, 0 = 201
+	failAfter int // NextDocument balikin 500 setelah halaman ke-n, 0 = gak pernah
+
+	mu       sync.Mutex
+	served   int
+	settings string
+	deleted  bool
+}
+
+func (s *esclStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/eSCL/ScannerCapabilities":
+		w.Header().Set("Content-Type", "text/xml")
+		io.WriteString(w, esclTestCaps)
+	case r.Method == http.MethodPost && r.URL.Path == "/eSCL/ScanJobs":
+		body, _ := io.ReadAll(r.Body)
+		s.settings = string(body)
+		if s.jobStatus != 0 {
+			w.WriteHeader(s.jobStatus)
+			io.WriteString(w, "ditolak stand-in")
+			return
+		}
+		w.Header().Set("Location", "/eSCL/ScanJobs/42")
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodGet && r.URL.Path == "/eSCL/ScanJobs/42/NextDocument":
+		switch {
+		case s.busy > 0:
+			s.busy--
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case s.failAfter > 0 && s.served == s.failAfter:
+			w.WriteHeader(http.StatusInternalServerError)
+			io.WriteString(w, "paper jam")
+		case s.served < s.pages:
+			s.served++
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write(testJPEG())
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case r.Method == http.MethodDelete && r.URL.Path == "/eSCL/ScanJobs/42":
+		s.deleted = true
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func testJPEG() []byte {
+	img := image.NewGray(image.Rect(0, 0, 40, 60))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, img, nil)
+	return buf.Bytes()
+}
+
+func esclTestScan(t *testing.T, s *esclStandIn, profile ESCLProfile) ([]string, *esclBackend, error) {
+	t.Helper()
+	old := esclBusyDelay
+	esclBusyDelay = time.Millisecond
+	t.Cleanup(func() { esclBusyDelay = old })
+
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	profile.Name = "MFP Lantai 2"
+	profile.URL = srv.URL + "/eSCL/"
+	b := &esclBackend{profile: profile}
+	lg := slog.New(slog.NewTextHandler(io.Discard, nil))
+	pages, err := b.Scan(context.Background(), lg, t.TempDir())
+	return pages, b, err
+}
+
+func TestESCLScan(t *testing.T) {
+	s := &esclStandIn{pages: 3, busy: 2}
+	pages, b, err := esclTestScan(t, s, ESCLProfile{Resolution: 280, ColorMode: "Grayscale8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 3 {
+		t.Fatalf("dapet %d halaman, harusnya 3", len(pages))
+	}
+	// Resolusi dibuletin ke yang didukung scanner, source default Feeder
+	for _, want := range []string{
+		"<pwg:InputSource>Feeder</pwg:InputSource>",
+		"<scan:XResolution>300</scan:XResolution>",
+		"<scan:ColorMode>Grayscale8</scan:ColorMode>",
+		"<pwg:Width>2480</pwg:Width>",
+	} {
+		if !strings.Contains(s.settings, want) {
+			t.Errorf("ScanSettings gak berisi %s:\n%s", want, s.settings)
+		}
+	}
+	if b.Pipeline().DPI != 300 {
+		t.Errorf("DPI pipeline %d, harusnya 300", b.Pipeline().DPI)
+	}
+	if s.deleted {
+		t.Error("job yang sukses gak boleh di-DELETE")
+	}
+}
+
+func TestESCLScanEmpty(t *testing.T) {
+	pages, _, err := esclTestScan(t, &esclStandIn{}, ESCLProfile{})
+	if err != nil || len(pages) != 0 {
+		t.Fatalf("feeder kosong: %d halaman, err %v", len(pages), err)
+	}
+}
+
+func TestESCLScanErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		standIn    *esclStandIn
+		profile    ESCLProfile
+		want       string
+		wantDelete bool
+	}{
+		{"scanner sibuk", &esclStandIn{jobStatus: http.StatusServiceUnavailable}, ESCLProfile{}, "Scanner eSCL sedang sibuk", false},
+		{"job ditolak", &esclStandIn{jobStatus: http.StatusConflict}, ESCLProfile{}, "ScanJob ditolak scanner (status 409): ditolak stand-in", false},
+		{"gagal di tengah", &esclStandIn{pages: 3, failAfter: 1}, ESCLProfile{}, "NextDocument gagal (status 500): paper jam", true},
+		{"sibuk terus", &esclStandIn{pages: 1, busy: esclBusyRetries + 1}, ESCLProfile{}, "terlalu lama sibuk", true},
+		{"color mode", &esclStandIn{pages: 1}, ESCLProfile{ColorMode: "BlackAndWhite1"}, "color mode BlackAndWhite1 tidak didukung", false},
+		{"platen gak ada", &esclStandIn{pages: 1}, ESCLProfile{Source: esclSourcePlaten}, "scanner tidak punya source Platen", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := esclTestScan(t, tt.standIn, tt.profile)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, harusnya berisi %q", err, tt.want)
+			}
+			if tt.standIn.deleted != tt.wantDelete {
+				t.Errorf("job di-DELETE = %v, harusnya %v", tt.standIn.deleted, tt.wantDelete)
+			}
+		})
+	}
+}
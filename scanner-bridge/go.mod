@@ -4,6 +4,7 @@ go 1.25.6
 
 require (
 	github.com/getlantern/systray v1.2.2
+	golang.org/x/image v0.24.0
 	gorm.io/driver/mysql v1.6.0
 	gorm.io/gorm v1.31.1
 )
@@ -24,5 +25,9 @@ require (
 	github.com/jung-kurt/gofpdf v1.16.2 // indirect
 	github.com/oxtoacart/bpool v0.0.0-20190530202638-03653db5a59c // indirect
 	golang.org/x/sys v0.1.0 // indirect
-	golang.org/x/text v0.20.0 // indirect
+	golang.org/x/text v0.22.0 // indirect
 )
+
+require dbclient v0.0.0-00010101000000-000000000000
+
+replace dbclient => ../dbclient
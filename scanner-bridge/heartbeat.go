@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"dbclient"
+	"log/slog"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Heartbeat: tiap beberapa detik bridge lapor ke POST /stations/heartbeat di
+// service db (versi, backend, scanner, profile, error terakhir), biar server
+// punya daftar station yang aktif.
+
+const defaultHeartbeatInterval = 60 * time.Second
+
+var heartbeatClient = &http.Client{Timeout: 15 * time.Second}
+
+func heartbeatInterval() time.Duration {
+	if config.HeartbeatSeconds > 0 {
+		return time.Duration(config.HeartbeatSeconds) * time.Second
+	}
+	return defaultHeartbeatInterval
+}
+
+// heartbeatLoop jalan terus di background. Gagal kirim cuma di-log sekali
+// sampai berhasil lagi, biar log gak penuh waktu server db mati.
+func heartbeatLoop() {
+	failing := false
+	for {
+		err := sendHeartbeat(buildHeartbeat())
+		switch {
+		case err != nil && !failing:
+			slog.Warn("Heartbeat ke server db gagal", "err", err)
+			failing = true
+		case err == nil && failing:
+			slog.Info("Heartbeat ke server db jalan lagi")
+			failing = false
+		}
+		time.Sleep(heartbeatInterval())
+	}
+}
+
+func buildHeartbeat() dbclient.Heartbeat {
+	hb := dbclient.Heartbeat{
+		StationID:       stationID(),
+		Name:            stationName(),
+		Version:         version,
+		ConfigVersion:   managed.version(),
+		OS:              runtime.GOOS + "/" + runtime.GOARCH,
+		Backends:        []string{},
+		Devices:         []dbclient.Device{},
+		IntervalSeconds: int(heartbeatInterval() / time.Second),
+	}
+	hb.Hostname, _ = os.Hostname()
+
+	profiles, err := profileNames()
+	if err != nil {
+		slog.Debug("Heartbeat: profil NAPS2 gak kebaca", "err", err)
+	}
+	hb.Profiles = profiles
+	if hb.Profiles == nil {
+		hb.Profiles = []string{}
+	}
+
+	// NAPS2 dianggap ada kalau exe-nya ada, scanner diambil dari profiles.xml
+	if _, err := os.Stat(naps2Path); err == nil {
+		hb.Backends = append(hb.Backends, "naps2")
+		naps2Profiles, _ := readNaps2Profiles()
+		seen := make(map[string]bool)
+		for _, p := range naps2Profiles {
+			if p.Device.Name == "" || seen[p.Device.ID] {
+				continue
+			}
+			seen[p.Device.ID] = true
+			hb.Devices = append(hb.Devices, dbclient.Device{Backend: "naps2", Name: p.Device.Name, Address: strings.TrimSpace(p.DriverName + " " + p.Device.ID)})
+		}
+	}
+	if len(config.ESCL) > 0 {
+		hb.Backends = append(hb.Backends, "escl")
+		for _, p := range config.ESCL {
+			hb.Devices = append(hb.Devices, dbclient.Device{Backend: "escl", Name: p.Name, Address: p.URL})
+		}
+	}
+	if len(config.Simulator) > 0 {
+		hb.Backends = append(hb.Backends, "simulator")
+		for _, p := range config.Simulator {
+			hb.Devices = append(hb.Devices, dbclient.Device{Backend: "simulator", Name: p.Name, Address: p.Scenario})
+		}
+	}
+
+	if msg, at := lastError.get(); msg != "" {
+		hb.LastError = msg
+		hb.LastErrorAt = &at
+	}
+	return hb
+}
+
+func sendHeartbeat(hb dbclient.Heartbeat) error {
+	return dbAPI(heartbeatClient).Heartbeat(context.Background(), hb)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"sort"
+	"time"
+)
+
+// Hot folder: buat MFP yang cuma bisa scan-to-folder. Bridge mantau folder,
+// ngumpulin file yang baru masuk jadi satu session (per jeda sepi atau per
+// pola nama file), terus diproses lewat pipeline yang sama kayak NAPS2.
+
+type HotFolderConfig struct {
+	Enabled bool   `json:"enabled"`
+	Path    string `json:"path"`
+	// Batch dianggap selesai kalau gak ada file baru selama ini (detik)
+	QuietSeconds int `json:"quiet_seconds"`
+	// Regex opsional, capture group pertama jadi kunci batch (mis. `^(\w+)_\d+\.jpg$`)
+	Pattern string `json:"pattern"`
+	Duplex  bool   `json:"duplex"`
+	Rotate  bool   `json:"rotate"`
+	// Nama profile yang dicatat di session
+	Profile string `json:"profile"`
+}
+
+var defaultHotFolderConfig = HotFolderConfig{
+	QuietSeconds: 15,
+	Profile:      "Hot Folder",
+}
+
+const hotFolderPoll = 2 * time.Second
+
+// Status file yang lagi dipantau: baru dianggap siap kalau ukuran & waktu
+// modifikasinya gak berubah (file dari network share bisa masih ditulis)
+type hotFile struct {
+	size      int64
+	modTime   time.Time
+	firstSeen time.Time
+	stable    bool
+}
+
+type hotFolder struct {
+	cfg     HotFolderConfig
+	pattern *regexp.Regexp
+	files   map[string]*hotFile
+}
+
+func startHotFolder(cfg HotFolderConfig) error {
+	if cfg.Path == "" {
+		return fmt.Errorf("path hot folder kosong")
+	}
+	if cfg.QuietSeconds <= 0 {
+		cfg.QuietSeconds = defaultHotFolderConfig.QuietSeconds
+	}
+
+	hf := &hotFolder{cfg: cfg, files: make(map[string]*hotFile)}
+	if cfg.Pattern != "" {
+		re, err := regexp.Compile(cfg.Pattern)
+		if err != nil {
+			return fmt.Errorf("pattern hot folder tidak valid: %v", err)
+		}
+		hf.pattern = re
+	}
+	for _, sub := range []string{"processed", "failed"} {
+		if err := os.MkdirAll(filepath.Join(cfg.Path, sub), 0755); err != nil {
+			return err
+		}
+	}
+
+	slog.Info("Hot folder aktif", "path", cfg.Path)
+	go hf.run()
+	return nil
+}
+
+func (hf *hotFolder) run() {
+	ticker := time.NewTicker(hotFolderPoll)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, ok := jobs.begin(); !ok {
+			return // Bridge lagi berhenti, file sisa diproses waktu start lagi
+		}
+		hf.poll()
+		jobs.end()
+	}
+}
+
+// batchKey: kunci pengelompokan file. Tanpa pattern semua file satu batch.
+func (hf *hotFolder) batchKey(name string) (string, bool) {
+	if hf.pattern == nil {
+		return "", true
+	}
+	m := hf.pattern.FindStringSubmatch(name)
+	if m == nil {
+		return "", false
+	}
+	if len(m) > 1 {
+		return m[1], true
+	}
+	return "", true
+}
+
+func (hf *hotFolder) poll() {
+	entries, err := os.ReadDir(hf.cfg.Path)
+	if err != nil {
+		slog.Error("Hot folder: gagal baca folder", "err", err)
+		return
+	}
+
+	now := time.Now()
+	present := make(map[string]bool)
+	for _, e := range entries {
+		if e.IsDir() || !ingestable(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		present[e.Name()] = true
+
+		f, ok := hf.files[e.Name()]
+		if !ok {
+			hf.files[e.Name()] = &hotFile{size: info.Size(), modTime: info.ModTime(), firstSeen: now}
+			continue
+		}
+		f.stable = f.size == info.Size() && f.modTime.Equal(info.ModTime())
+		if !f.stable {
+			// Masih ditulis, hitung ulang jeda sepinya
+			f.size, f.modTime, f.firstSeen = info.Size(), info.ModTime(), now
+		}
+	}
+	for name := range hf.files {
+		if !present[name] {
+			delete(hf.files, name)
+		}
+	}
+
+	// Kelompokin per batch, batch siap kalau semua file stabil dan udah sepi
+	type batch struct {
+		names  []string
+		ready  bool
+		latest time.Time
+	}
+	batches := make(map[string]*batch)
+	for name, f := range hf.files {
+		key, ok := hf.batchKey(name)
+		if !ok {
+			continue
+		}
+		b, exists := batches[key]
+		if !exists {
+			b = &batch{ready: true}
+			batches[key] = b
+		}
+		b.names = append(b.names, name)
+		b.ready = b.ready && f.stable
+		if f.firstSeen.After(b.latest) {
+			b.latest = f.firstSeen
+		}
+	}
+
+	quiet := time.Duration(hf.cfg.QuietSeconds) * time.Second
+	for key, b := range batches {
+		if !b.ready || now.Sub(b.latest) < quiet {
+			continue
+		}
+		sort.Strings(b.names)
+		hf.ingest(key, b.names)
+		for _, name := range b.names {
+			delete(hf.files, name)
+		}
+	}
+}
+
+// ingest mindahin file batch keluar dari folder pantauan, lalu diproses jadi session
+func (hf *hotFolder) ingest(key string, names []string) {
+	batchID := fmt.Sprintf("%d", time.Now().UnixNano())
+	if key != "" {
+		batchID += "_" + key
+	}
+	lg := slog.With("batch", batchID)
+	lg.Info("Hot folder: memproses batch", "files", len(names))
+
+	workDir := filepath.Join(hf.cfg.Path, "processed", batchID)
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		lg.Error("Hot folder: gagal buat folder batch", "err", err)
+		return
+	}
+	var files []string
+	for _, name := range names {
+		dst := filepath.Join(workDir, name)
+		if err := os.Rename(filepath.Join(hf.cfg.Path, name), dst); err != nil {
+			lg.Error("Hot folder: gagal mindahin file", "file", name, "err", err)
+			continue
+		}
+		files = append(files, dst)
+	}
+	if len(files) == 0 {
+		return
+	}
+
+	fail := func() {
+		recordFailure(SourceHotFolder, failProcessing)
+		failedDir := filepath.Join(hf.cfg.Path, "failed", batchID)
+		if err := os.Rename(workDir, failedDir); err != nil {
+			lg.Error("Hot folder: gagal mindahin batch ke failed", "err", err)
+		}
+	}
+	// File rusak jangan sampai bikin goroutine hot folder (dan bridge) mati
+	defer func() {
+		if p := recover(); p != nil {
+			lg.Error("Hot folder: batch bikin panic", "panic", p, "stack", string(debug.Stack()))
+			fail()
+		}
+	}()
+
+	sessionID, err := hf.buildSession(lg, files)
+	if err != nil {
+		lg.Error("Hot folder: batch gagal", "err", err)
+		fail()
+		return
+	}
+	scansTotal.inc(SourceHotFolder, "success")
+	lg.Info("Hot folder: batch jadi session", "session", sessionID)
+}
+
+func (hf *hotFolder) buildSession(lg *slog.Logger, files []string) (string, error) {
+	tempDir := filepath.Join(os.TempDir(), fmt.Sprintf("hotfolder_job_%d", time.Now().UnixNano()))
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tempDir)
+
+	pages, err := normalizeFiles(files, tempDir)
+	if err != nil {
+		return "", err
+	}
+
+	sess, err := sessions.create(hf.cfg.Profile, SourceHotFolder)
+	if err != nil {
+		return "", err
+	}
+
+	lg = lg.With("session", sess.ID)
+	added := buildSheets(lg, pages, pipelineOptions{Rotate: hf.cfg.Rotate, Duplex: hf.cfg.Duplex}, hf.cfg.Profile, sess.Dir)
+	if len(added) == 0 {
+		sessions.remove(sess.ID)
+		return "", fmt.Errorf("tidak ada halaman yang berhasil diproses")
+	}
+	if err := sessions.apply(sess.ID, ModeAppend, 0, added); err != nil {
+		sessions.remove(sess.ID)
+		return "", err
+	}
+	pagesTotal.add(float64(countPages(added)), SourceHotFolder)
+	return sess.ID, nil
+}
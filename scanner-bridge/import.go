@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Import: BAPP yang dikirim sekolah lewat email (PDF) atau foto HP bisa
+// dimasukin ke session lewat upload, terus diproses kayak hasil scan.
+
+const (
+	maxImportBytes = 200 << 20 // Total upload
+	importMemory   = 32 << 20  // Sisanya ditulis ke temp file sama net/http
+)
+
+// importHandler: POST /import (multipart/form-data, field "files", boleh banyak)
+// Parameter opsional sama kayak /scan: session, mode, sheet. Tambahan:
+// duplex=true kalau file berurutan depan/belakang, deskew=false buat matiin deskew.
+func importHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w, r)
+	if r.Method == "OPTIONS" {
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	job := newJobID()
+	lg := slog.With("job", job)
+	// Semua jalur gagal dicatat di metrics dengan backend "import"
+	fail := func(status int, class, message string) {
+		recordFailure(SourceImport, class)
+		writeError(w, status, message)
+	}
+
+	if _, ok := jobs.begin(); !ok {
+		writeError(w, http.StatusServiceUnavailable, "Scanner Bridge sedang berhenti / restart, coba lagi sebentar")
+		return
+	}
+	defer jobs.end()
+
+	edit, herr := parseSessionEdit(query)
+	if herr != nil {
+		fail(herr.Status, failInvalid, herr.Message)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	if err := r.ParseMultipartForm(importMemory); err != nil {
+		fail(http.StatusBadRequest, failInvalid, "Upload tidak valid atau terlalu besar: "+err.Error())
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	uploads := r.MultipartForm.File["files"]
+	if len(uploads) == 0 {
+		fail(http.StatusBadRequest, failInvalid, "Tidak ada file yang diupload (field files)")
+		return
+	}
+
+	tempDir := filepath.Join(os.TempDir(), "import_job_"+job)
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		lg.Error("Gagal buat temp dir", "err", err)
+		fail(http.StatusInternalServerError, failInternal, "Gagal membuat temporary directory")
+		return
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Simpan upload pakai nama urut (nama asli dari browser gak dipercaya)
+	var files []string
+	for i, fh := range uploads {
+		ext := filepath.Ext(fh.Filename)
+		if !ingestable(fh.Filename) {
+			fail(http.StatusBadRequest, failInvalid, fmt.Sprintf("File %s: format tidak didukung (JPEG, PNG, TIFF, PDF)", fh.Filename))
+			return
+		}
+		dst := filepath.Join(tempDir, fmt.Sprintf("upload_%04d%s", i+1, ext))
+		if err := saveUpload(fh, dst); err != nil {
+			fail(http.StatusInternalServerError, failInternal, fmt.Sprintf("File %s: gagal disimpan: %v", fh.Filename, err))
+			return
+		}
+		files = append(files, dst)
+	}
+
+	pagesDir := filepath.Join(tempDir, "pages")
+	if err := os.MkdirAll(pagesDir, 0755); err != nil {
+		fail(http.StatusInternalServerError, failInternal, "Gagal membuat temporary directory")
+		return
+	}
+	pages, err := normalizeFiles(files, pagesDir)
+	if err != nil {
+		lg.Warn("Import gagal baca file", "err", err)
+		fail(http.StatusBadRequest, failInvalid, "Gagal membaca file: "+err.Error())
+		return
+	}
+
+	profile := "Import"
+	if edit.Session != nil {
+		profile = edit.Session.Profile
+	}
+	opts := pipelineOptions{
+		Duplex: query.Get("duplex") == "true",
+		Deskew: query.Get("deskew") != "false",
+	}
+	sess, added, failure := commitSheets(w, lg, edit, profile, SourceImport, pages, opts)
+	if failure != "" {
+		recordFailure(SourceImport, failure)
+		return
+	}
+	scansTotal.inc(SourceImport, "success")
+	lg.Info("Import sukses", "session", sess.ID, "files", len(uploads), "sheets", added, "mode", edit.Mode)
+}
+
+func saveUpload(fh *multipart.FileHeader, dst string) error {
+	src, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "golang.org/x/image/tiff"
+)
+
+// Ingest: ubah file dari luar NAPS2 (gambar / PDF) jadi JPEG berurutan,
+// biar bisa masuk buildSheets kayak hasil scan biasa.
+
+// Ekstensi file yang bisa di-ingest. TIFF harus satu halaman, multi-halaman
+// ditolak (decoder cuma bisa baca halaman pertama).
+var ingestExts = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".tif": true, ".tiff": true, ".pdf": true}
+
+func ingestable(name string) bool {
+	return ingestExts[strings.ToLower(filepath.Ext(name))]
+}
+
+// normalizeFiles nulis semua halaman dari files ke dir sebagai page_NNNN.jpg
+// sesuai urutan, lalu balikin path-path hasilnya
+func normalizeFiles(files []string, dir string) ([]string, error) {
+	var out []string
+	add := func(data []byte) error {
+		path := filepath.Join(dir, fmt.Sprintf("page_%04d.jpg", len(out)+1))
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return err
+		}
+		out = append(out, path)
+		return nil
+	}
+
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		switch strings.ToLower(filepath.Ext(f)) {
+		case ".pdf":
+			pages, err := extractPDFImages(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", filepath.Base(f), err)
+			}
+			for _, p := range pages {
+				if err := add(p); err != nil {
+					return nil, err
+				}
+			}
+		case ".jpg", ".jpeg":
+			if err := checkImageConfig(data); err != nil {
+				return nil, fmt.Errorf("%s: %v", filepath.Base(f), err)
+			}
+			if err := add(data); err != nil {
+				return nil, err
+			}
+		default:
+			jpg, err := toJPEG(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", filepath.Base(f), err)
+			}
+			if err := add(jpg); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out, nil
+}
+
+// Batas ukuran gambar sebelum di-decode penuh (samain kayak maxPageSide /
+// maxPagePixels di service db). Header beberapa KB bisa ngaku 60000x60000,
+// kalau langsung di-decode bridge alokasi bergiga-giga.
+const (
+	maxImageSide   = 15000
+	maxImagePixels = 40 << 20
+)
+
+func checkImageSize(w, h int) error {
+	if w > maxImageSide || h > maxImageSide || int64(w)*int64(h) > maxImagePixels {
+		return fmt.Errorf("gambar %dx%d terlalu besar (maks %d px per sisi, %d MP)", w, h, maxImageSide, maxImagePixels>>20)
+	}
+	return nil
+}
+
+// checkImageConfig baca header gambar aja (DecodeConfig) lalu cek ukurannya
+func checkImageConfig(data []byte) error {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("gagal baca header gambar: %v", err)
+	}
+	return checkImageSize(cfg.Width, cfg.Height)
+}
+
+// decodeJPEG: jpeg.Decode yang ukurannya dicek dulu
+func decodeJPEG(data []byte) (image.Image, error) {
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("gagal decode jpeg: %v", err)
+	}
+	if err := checkImageSize(cfg.Width, cfg.Height); err != nil {
+		return nil, err
+	}
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("gagal decode jpeg: %v", err)
+	}
+	return img, nil
+}
+
+// tiffPages ngitung jumlah IFD (halaman) di file TIFF
+func tiffPages(data []byte) (int, error) {
+	if len(data) < 8 {
+		return 0, errors.New("file TIFF kepotong")
+	}
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0, errors.New("bukan file TIFF")
+	}
+	pages := 0
+	seen := make(map[uint32]bool)
+	for off := order.Uint32(data[4:8]); off != 0; pages++ {
+		// IFD yang muter balik ke IFD sebelumnya = file rusak
+		if seen[off] || int64(off)+2 > int64(len(data)) {
+			return 0, errors.New("struktur TIFF rusak")
+		}
+		seen[off] = true
+		n := int64(order.Uint16(data[off:]))
+		next := int64(off) + 2 + n*12
+		if next+4 > int64(len(data)) {
+			return 0, errors.New("struktur TIFF rusak")
+		}
+		off = order.Uint32(data[next:])
+	}
+	return pages, nil
+}
+
+// toJPEG decode format gambar lain (PNG/TIFF) terus encode ulang jadi JPEG
+func toJPEG(data []byte) ([]byte, error) {
+	if err := checkImageConfig(data); err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(data, []byte("II*\x00")) || bytes.HasPrefix(data, []byte("MM\x00*")) {
+		n, err := tiffPages(data)
+		if err != nil {
+			return nil, err
+		}
+		// Jangan sampai halaman 2 dst hilang diam-diam
+		if n > 1 {
+			return nil, fmt.Errorf("TIFF berisi %d halaman, belum didukung. Export per halaman atau jadi PDF dulu", n)
+		}
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("gagal decode gambar: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, fmt.Errorf("gagal encode jpeg: %v", err)
+	}
+	return buf.Bytes(), nil
+}
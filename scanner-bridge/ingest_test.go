@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/tiff"
+)
+
+// hugeJPEG: JPEG kecil yang header SOF-nya diubah ngaku 60000x60000
+func hugeJPEG(t *testing.T) []byte {
+	t.Helper()
+	data := append([]byte(nil), testJPEG()...)
+	i := bytes.Index(data, []byte{0xFF, 0xC0})
+	if i < 0 {
+		t.Fatal("SOF0 gak ketemu")
+	}
+	binary.BigEndian.PutUint16(data[i+5:], 60000) // Height
+	binary.BigEndian.PutUint16(data[i+7:], 60000) // Width
+	return data
+}
+
+// testTIFF bikin TIFF dengan jumlah halaman (IFD) tertentu. Halaman kedua
+// dst cuma IFD kosong, cukup buat ngetes hitungan halaman.
+func testTIFF(t *testing.T, pages int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := tiff.Encode(&buf, image.NewGray(image.Rect(0, 0, 20, 30)), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	order := binary.LittleEndian
+	if string(data[:2]) == "MM" {
+		t.Fatal("encoder ngasilin big endian, test-nya perlu disesuain")
+	}
+	next := order.Uint32(data[4:]) // Posisi pointer "IFD berikutnya"
+	next += 2 + uint32(order.Uint16(data[next:]))*12
+	for i := 1; i < pages; i++ {
+		for len(data)%2 != 0 {
+			data = append(data, 0)
+		}
+		order.PutUint32(data[next:], uint32(len(data)))
+		next = uint32(len(data)) + 2
+		data = append(data, 0, 0, 0, 0, 0, 0) // 0 entry, next = 0
+	}
+	return data
+}
+
+func TestImageSizeLimits(t *testing.T) {
+	huge := hugeJPEG(t)
+	if _, err := decodeJPEG(huge); err == nil || !strings.Contains(err.Error(), "terlalu besar") {
+		t.Errorf("decodeJPEG: err = %v", err)
+	}
+	if _, err := analyzeQuality(huge, QualityThresholds{}); err == nil || !strings.Contains(err.Error(), "terlalu besar") {
+		t.Errorf("analyzeQuality: err = %v", err)
+	}
+	if _, err := decodeJPEG(testJPEG()); err != nil {
+		t.Errorf("JPEG normal ditolak: %v", err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "besar.jpg")
+	os.WriteFile(path, huge, 0644)
+	if _, err := normalizeFiles([]string{path}, dir); err == nil || !strings.Contains(err.Error(), "terlalu besar") {
+		t.Errorf("normalizeFiles: err = %v", err)
+	}
+	if _, err := processImage(nil, path, pipelineOptions{Deskew: true}); err == nil || !strings.Contains(err.Error(), "terlalu besar") {
+		t.Errorf("processImage: err = %v", err)
+	}
+}
+
+func TestToJPEGTIFF(t *testing.T) {
+	single := testTIFF(t, 1)
+	if n, err := tiffPages(single); n != 1 || err != nil {
+		t.Fatalf("tiffPages = %d, %v, harusnya 1", n, err)
+	}
+	if _, err := toJPEG(single); err != nil {
+		t.Fatalf("TIFF satu halaman ditolak: %v", err)
+	}
+
+	multi := testTIFF(t, 3)
+	if n, err := tiffPages(multi); n != 3 || err != nil {
+		t.Fatalf("tiffPages = %d, %v, harusnya 3", n, err)
+	}
+	if _, err := toJPEG(multi); err == nil || !strings.Contains(err.Error(), "3 halaman") {
+		t.Errorf("TIFF multi-halaman harusnya ditolak, err = %v", err)
+	}
+
+	// IFD yang nunjuk ke dirinya sendiri gak boleh bikin loop
+	loop := append([]byte(nil), single...)
+	off := binary.LittleEndian.Uint32(loop[4:])
+	next := off + 2 + uint32(binary.LittleEndian.Uint16(loop[off:]))*12
+	binary.LittleEndian.PutUint32(loop[next:], off)
+	if _, err := tiffPages(loop); err == nil {
+		t.Error("TIFF dengan IFD muter harusnya error")
+	}
+}
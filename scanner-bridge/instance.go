@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Single instance: exe yang di-double-click dua kali gak boleh jalan dobel.
+// Pakai named mutex Windows (otomatis lepas kalau prosesnya mati / crash),
+// instance kedua ngecek /health instance pertama lalu ngasih tau operator.
+
+const (
+	instanceMutexName = `Local\OWOScannerBridge`
+	healthApp         = "owo-scanner-bridge"
+
+	errorAlreadyExists = 183 // ERROR_ALREADY_EXISTS
+
+	mbYesNo           = 0x00000004
+	mbIconInformation = 0x00000040
+	mbIconError       = 0x00000010
+	mbSetForeground   = 0x00010000
+	idYes             = 6
+)
+
+var (
+	createMutex = kernel32.NewProc("CreateMutexW")
+	closeHandle = kernel32.NewProc("CloseHandle")
+	messageBox  = user32.NewProc("MessageBoxW")
+)
+
+// Handle mutex sengaja gak pernah ditutup, lepas sendiri waktu proses exit
+var instanceMutex uintptr
+
+// tryInstanceMutex: true kalau belum ada instance lain yang megang mutex
+func tryInstanceMutex() (bool, error) {
+	name, err := syscall.UTF16PtrFromString(instanceMutexName)
+	if err != nil {
+		return false, err
+	}
+	h, _, callErr := createMutex.Call(0, 0, uintptr(unsafe.Pointer(name)))
+	if h == 0 {
+		return false, callErr
+	}
+	if errno, ok := callErr.(syscall.Errno); ok && errno == errorAlreadyExists {
+		closeHandle.Call(h)
+		return false, nil
+	}
+	instanceMutex = h
+	return true, nil
+}
+
+// acquireInstance dipanggil sebelum tray jalan. False = ada bridge lain yang
+// lagi jalan normal, proses ini harus langsung keluar.
+func acquireInstance() bool {
+	ok, err := tryInstanceMutex()
+	if err != nil {
+		// Gak bisa dicek, mending tetap jalan daripada bridge gak bisa start sama sekali
+		slog.Warn("Gagal cek instance lain, lanjut jalan", "err", err)
+		return true
+	}
+	if ok {
+		return true
+	}
+
+	if h, err := probeHealth(); err == nil {
+		slog.Info("Scanner Bridge sudah jalan, instance ini keluar", "pid", h.PID, "station", h.StationID)
+		msg := fmt.Sprintf("Scanner Bridge sudah berjalan (PID %d), cek ikon di system tray.\n\nBuka halaman scanner lokal?", h.PID)
+		if showMessage(msg, mbYesNo|mbIconInformation) == idYes {
+			if err := openLocalUI(""); err != nil {
+				slog.Error("Gagal buka UI lokal", "err", err)
+			}
+		}
+		return false
+	}
+
+	// Mutex ada tapi /health gak jawab: biasanya instance lama lagi berhenti
+	// (restart dari tray), tunggu sampai mutex-nya lepas
+	slog.Info("Nunggu instance Scanner Bridge sebelumnya berhenti")
+	deadline := time.Now().Add(bindRetryTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(bindRetryInterval)
+		if ok, err := tryInstanceMutex(); ok || err != nil {
+			return true
+		}
+	}
+	showMessage("Scanner Bridge lain masih jalan tapi tidak merespon.\n\nTutup lewat Task Manager (OWO Scanner Bridge) lalu jalankan ulang.", mbIconError)
+	return false
+}
+
+func showMessage(text string, flags uintptr) int {
+	t, _ := syscall.UTF16PtrFromString(text)
+	c, _ := syscall.UTF16PtrFromString("OWO Scanner Bridge")
+	ret, _, _ := messageBox.Call(0, uintptr(unsafe.Pointer(t)), uintptr(unsafe.Pointer(c)), flags|mbSetForeground)
+	return int(ret)
+}
+
+type HealthInfo struct {
+	App       string `json:"app"`
+	Version   string `json:"version"`
+	StationID string `json:"station_id"`
+	PID       int    `json:"pid"`
+}
+
+// healthHandler: GET /health, tanpa pairing. Dipakai instance kedua & monitoring.
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data": HealthInfo{
+			App:       healthApp,
+			Version:   version,
+			StationID: stationID(),
+			PID:       os.Getpid(),
+		},
+	})
+}
+
+var errNotBridge = errors.New("bukan Scanner Bridge")
+
+// probeHealth nanya /health ke port bridge. errNotBridge kalau yang jawab
+// program lain, body-nya ikut dibalikin buat pesan error.
+func probeHealth() (*HealthInfo, error) {
+	h, _, err := probePort()
+	return h, err
+}
+
+func probePort() (*HealthInfo, []byte, error) {
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get("http://127.0.0.1" + bridgePort + "/health")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	var res struct {
+		Data HealthInfo `json:"data"`
+	}
+	if json.Unmarshal(body, &res) != nil || res.Data.App != healthApp {
+		return nil, body, errNotBridge
+	}
+	return &res.Data, body, nil
+}
+
+// portConflictMessage: pesan jelas buat operator kalau port bridge kepake
+func portConflictMessage(bindErr error) string {
+	h, body, err := probePort()
+	switch {
+	case err == nil:
+		return fmt.Sprintf("Port %s dipakai Scanner Bridge lain (PID %d, station %s). Tutup yang itu dulu lalu Restart dari tray.", bridgePort, h.PID, h.StationID)
+	case errors.Is(err, errNotBridge) && bytes.Contains(body, []byte("API is running")):
+		return fmt.Sprintf("Port %s dipakai Database API (service db) di PC ini. Jalankan service db di port lain (API_PORT) lalu Restart dari tray.", bridgePort)
+	}
+	if name, pid := portOwner(bridgePort); pid != "" {
+		return fmt.Sprintf("Port %s dipakai program lain: %s (PID %s). Tutup program itu lalu Restart dari tray.", bridgePort, name, pid)
+	}
+	return fmt.Sprintf("Scanner Bridge gagal buka port %s: %v", bridgePort, bindErr)
+}
+
+// portOwner cari proses yang LISTENING di port lewat netstat + tasklist
+func portOwner(port string) (name, pid string) {
+	out, err := exec.Command("netstat", "-ano", "-p", "TCP").Output()
+	if err != nil {
+		return "", ""
+	}
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		// TCP    0.0.0.0:5000    0.0.0.0:0    LISTENING    1234
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 5 && fields[3] == "LISTENING" && strings.HasSuffix(fields[1], port) {
+			pid = fields[4]
+			break
+		}
+	}
+	if pid == "" {
+		return "", ""
+	}
+
+	name = "tidak diketahui"
+	out, err = exec.Command("tasklist", "/FI", "PID eq "+pid, "/FO", "CSV", "/NH").Output()
+	if err == nil {
+		// "node.exe","1234","Console","1","50.000 K"
+		if rec, err := csv.NewReader(bytes.NewReader(out)).Read(); err == nil && len(rec) > 1 {
+			name = rec[0]
+		}
+	}
+	return name, pid
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/getlantern/systray"
+)
+
+// Lifecycle: exit / restart dari tray nunggu scan yang lagi jalan selesai
+// (maksimal shutdownDrainTimeout), baru dibatalin paksa. Server HTTP ditutup
+// pakai http.Server.Shutdown biar port :5000 beneran lepas sebelum proses
+// baru jalan.
+
+const (
+	shutdownDrainTimeout = 60 * time.Second // Nunggu scan / import selesai
+	shutdownCancelGrace  = 10 * time.Second // Setelah dibatalin, nunggu handler beres
+	bindRetryTimeout     = 30 * time.Second // Proses baru nunggu port lepas
+	bindRetryInterval    = 500 * time.Millisecond
+)
+
+// jobTracker ngitung job (scan, import, hot folder, kirim outbox) yang lagi
+// jalan. Context-nya dibatalin kalau shutdown kelamaan.
+type jobTracker struct {
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	closing bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+func newJobTracker() *jobTracker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &jobTracker{ctx: ctx, cancel: cancel}
+}
+
+var jobs = newJobTracker()
+
+// begin daftarin job baru, false kalau bridge lagi berhenti. Kalau true,
+// wajib dipasangin defer jobs.end().
+func (t *jobTracker) begin() (context.Context, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closing {
+		return nil, false
+	}
+	t.wg.Add(1)
+	return t.ctx, true
+}
+
+func (t *jobTracker) end() {
+	t.wg.Done()
+}
+
+// wait nunggu semua job selesai, false kalau timeout duluan
+func (t *jobTracker) wait(timeout time.Duration) bool {
+	t.mu.Lock()
+	t.closing = true
+	t.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// errJobCanceled: job dibatalin karena bridge exit / restart
+var errJobCanceled = errors.New("dibatalkan karena Scanner Bridge berhenti / restart")
+
+// sleepCtx: time.Sleep yang bisa dibatalin
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return errJobCanceled
+	case <-t.C:
+		return nil
+	}
+}
+
+// Server HTTP & HTTPS yang lagi jalan, ditutup bareng waktu shutdown
+var (
+	serversMu sync.Mutex
+	servers   []*http.Server
+)
+
+// serve bind addr (nunggu kalau port masih dipegang proses lama) lalu serve
+func serve(srv *http.Server, serveFn func(net.Listener) error) error {
+	ln, err := listenRetry(srv.Addr)
+	if err != nil {
+		return err
+	}
+	serversMu.Lock()
+	servers = append(servers, srv)
+	serversMu.Unlock()
+
+	if err := serveFn(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// listenRetry: habis restart, proses lama bisa aja belum lepas port-nya
+func listenRetry(addr string) (net.Listener, error) {
+	deadline := time.Now().Add(bindRetryTimeout)
+	logged := false
+	for {
+		ln, err := net.Listen("tcp", addr)
+		if err == nil {
+			if logged {
+				slog.Info("Port udah lepas, lanjut", "addr", addr)
+			}
+			return ln, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, err
+		}
+		if !logged {
+			slog.Warn("Port masih dipakai, nunggu dilepas", "addr", addr, "err", err)
+			logged = true
+		}
+		time.Sleep(bindRetryInterval)
+	}
+}
+
+var stopOnce sync.Once
+
+// stopBridge: stop terima job baru, tunggu / batalin job yang jalan, tutup
+// server HTTP. Aman dipanggil berkali-kali.
+func stopBridge(reason string) {
+	stopOnce.Do(func() {
+		slog.Info("Scanner Bridge berhenti, nunggu job yang jalan", "reason", reason)
+		systray.SetTooltip("OWO Scanner Bridge - menunggu scan selesai...")
+
+		if !jobs.wait(shutdownDrainTimeout) {
+			slog.Warn("Job belum selesai, dibatalin paksa", "timeout", shutdownDrainTimeout)
+			jobs.cancel()
+			if !jobs.wait(shutdownCancelGrace) {
+				slog.Error("Job masih jalan setelah dibatalin, tetap lanjut berhenti")
+			}
+		}
+
+		serversMu.Lock()
+		list := servers
+		serversMu.Unlock()
+		for _, srv := range list {
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownCancelGrace)
+			if err := srv.Shutdown(ctx); err != nil {
+				slog.Warn("Server gak berhenti dengan rapi, ditutup paksa", "addr", srv.Addr, "err", err)
+				srv.Close()
+			}
+			cancel()
+		}
+		slog.Info("Scanner Bridge berhenti")
+	})
+}
+
+// exitBridge dipanggil dari tray Exit / sinyal console
+func exitBridge(reason string) {
+	stopBridge(reason)
+	systray.Quit()
+}
+
+// handleSignals: Ctrl+C di console, console ditutup, atau Windows logoff / shutdown.
+// Windows cuma ngasih beberapa detik buat yang terakhir, jadi ini best effort.
+func handleSignals() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	sig := <-ch
+	exitBridge(sig.String())
+}
+
+// restartBridge: berhenti dulu sampai port lepas, baru jalanin proses baru.
+// Error cuma kalau belum sempat berhenti (bridge masih jalan normal).
+func restartBridge() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	stopBridge("restart")
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	// Detach process to ensure clean restart
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		slog.Error("Gagal restart, bridge udah berhenti, jalankan ulang manual", "err", err)
+	}
+	systray.Quit()
+	return nil
+}
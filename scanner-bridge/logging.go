@@ -0,0 +1,410 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Log: selain ke console (yang sering di-hide operator), semua log ditulis
+// JSON per baris ke %AppData%\OWO Scanner Bridge\logs\bridge.log, dirotasi
+// per ukuran. Bisa dilihat lewat GET /logs atau diexport dari tray.
+
+type LogConfig struct {
+	Level     string `json:"level"`       // debug, info, warn, error
+	MaxSizeMB int    `json:"max_size_mb"` // Rotasi kalau bridge.log lewat segini
+	MaxFiles  int    `json:"max_files"`   // Jumlah file lama yang disimpen (bridge.log.1, .2, ...)
+}
+
+var defaultLogConfig = LogConfig{
+	Level:     "info",
+	MaxSizeMB: 10,
+	MaxFiles:  5,
+}
+
+const logFileName = "bridge.log"
+
+var (
+	logLevel = new(slog.LevelVar)
+	logFile  *rotatingFile
+)
+
+// initLogging dipanggil paling awal (sebelum config kebaca), pakai batas default
+func initLogging() error {
+	console := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel})
+	slog.SetDefault(slog.New(teeHandler{console, lastError}))
+
+	dir, err := logDir()
+	if err != nil {
+		return err
+	}
+	f, err := openRotatingFile(filepath.Join(dir, logFileName), defaultLogConfig)
+	if err != nil {
+		return err
+	}
+	logFile = f
+	file := slog.NewJSONHandler(f, &slog.HandlerOptions{Level: logLevel})
+	slog.SetDefault(slog.New(teeHandler{console, file, lastError}))
+	return nil
+}
+
+// applyLogConfig dipanggil setelah config.json kebaca
+func applyLogConfig(cfg LogConfig) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		slog.Warn("Level log tidak dikenal, pakai info", "level", cfg.Level)
+		level = slog.LevelInfo
+	}
+	logLevel.Set(level)
+	if logFile != nil {
+		logFile.setLimits(cfg)
+	}
+}
+
+func logDir() (string, error) {
+	dir, err := appDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "logs")
+	return dir, os.MkdirAll(dir, 0755)
+}
+
+// newJobID: ID per request scan / import, biar log satu job gampang difilter
+func newJobID() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+// teeHandler nerusin record ke beberapa handler sekaligus
+type teeHandler []slog.Handler
+
+func (t teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range t {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make(teeHandler, len(t))
+	for i, h := range t {
+		out[i] = h.WithAttrs(attrs)
+	}
+	return out
+}
+
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	out := make(teeHandler, len(t))
+	for i, h := range t {
+		out[i] = h.WithGroup(name)
+	}
+	return out
+}
+
+// lastErrorHandler nyimpen log level error terakhir, dikirim lewat heartbeat
+// biar dashboard bisa liat station mana yang lagi bermasalah
+type lastErrorHandler struct {
+	mu  sync.Mutex
+	msg string
+	at  time.Time
+}
+
+var lastError = &lastErrorHandler{}
+
+func (h *lastErrorHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelError
+}
+
+func (h *lastErrorHandler) Handle(_ context.Context, r slog.Record) error {
+	msg := r.Message
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == "err" {
+			msg += ": " + a.Value.String()
+			return false
+		}
+		return true
+	})
+	h.mu.Lock()
+	h.msg, h.at = msg, r.Time
+	h.mu.Unlock()
+	return nil
+}
+
+// Atribut (job, session, ...) gak ikut disimpen, cukup pesan + err
+func (h *lastErrorHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *lastErrorHandler) WithGroup(string) slog.Handler      { return h }
+
+func (h *lastErrorHandler) get() (string, time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.msg, h.at
+}
+
+// rotatingFile: io.Writer ke file yang dirotasi kalau ukurannya lewat batas
+type rotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	f        *os.File
+	size     int64
+	maxBytes int64
+	maxFiles int
+}
+
+func openRotatingFile(path string, cfg LogConfig) (*rotatingFile, error) {
+	r := &rotatingFile{path: path}
+	r.setLimits(cfg)
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) setLimits(cfg LogConfig) {
+	if cfg.MaxSizeMB <= 0 {
+		cfg.MaxSizeMB = defaultLogConfig.MaxSizeMB
+	}
+	if cfg.MaxFiles <= 0 {
+		cfg.MaxFiles = defaultLogConfig.MaxFiles
+	}
+	r.mu.Lock()
+	r.maxBytes = int64(cfg.MaxSizeMB) << 20
+	r.maxFiles = cfg.MaxFiles
+	r.mu.Unlock()
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f, r.size = f, info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.size > 0 && r.size+int64(len(p)) > r.maxBytes {
+		if err := r.rotate(); err != nil {
+			// Gagal rotasi (mis. file dikunci antivirus), tetap nulis ke file lama
+			fmt.Fprintln(os.Stderr, "Gagal rotasi log:", err)
+		}
+	}
+	if r.f == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate: bridge.log -> bridge.log.1 -> bridge.log.2 ..., yang paling lama dibuang
+func (r *rotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	r.f = nil
+
+	os.Remove(fmt.Sprintf("%s.%d", r.path, r.maxFiles))
+	for i := r.maxFiles - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	if err := os.Rename(r.path, r.path+".1"); err != nil {
+		return err
+	}
+	return r.open()
+}
+
+// files: file log dari yang paling baru (bridge.log, bridge.log.1, ...)
+func (r *rotatingFile) files() []string {
+	r.mu.Lock()
+	max := r.maxFiles
+	r.mu.Unlock()
+
+	paths := []string{r.path}
+	for i := 1; i <= max; i++ {
+		p := fmt.Sprintf("%s.%d", r.path, i)
+		if _, err := os.Stat(p); err != nil {
+			break
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// recentLogs: maksimal limit baris terakhir yang levelnya >= minLevel dan
+// (kalau filter diisi) mengandung teks filter, urut dari yang lama
+func recentLogs(limit int, minLevel slog.Level, filter string) ([]json.RawMessage, error) {
+	if logFile == nil {
+		return nil, fmt.Errorf("log file tidak aktif")
+	}
+
+	var entries []json.RawMessage
+	for _, path := range logFile.files() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var matched []json.RawMessage
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		sc.Buffer(make([]byte, 64<<10), 1<<20)
+		for sc.Scan() {
+			line := sc.Bytes()
+			if filter != "" && !bytes.Contains(line, []byte(filter)) {
+				continue
+			}
+			var rec struct {
+				Level slog.Level `json:"level"`
+			}
+			if json.Unmarshal(line, &rec) != nil || rec.Level < minLevel {
+				continue
+			}
+			matched = append(matched, json.RawMessage(append([]byte(nil), line...)))
+		}
+		// File lama ditaruh di depan
+		entries = append(matched, entries...)
+		if len(entries) >= limit {
+			break
+		}
+	}
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries, nil
+}
+
+// exportLogs nulis semua file log ke zip (buat dilampirin di tiket support)
+func exportLogs(w io.Writer) error {
+	if logFile == nil {
+		return fmt.Errorf("log file tidak aktif")
+	}
+	zw := zip.NewWriter(w)
+	for _, path := range logFile.files() {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		entry, err := zw.Create(filepath.Base(path))
+		if err == nil {
+			_, err = io.Copy(entry, f)
+		}
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// exportLogsToFile: dipakai tray, zip disimpen di folder logs lalu dibuka di Explorer
+func exportLogsToFile() error {
+	dir, err := logDir()
+	if err != nil {
+		return err
+	}
+	out := filepath.Join(dir, fmt.Sprintf("bridge-log-%s-%s.zip", stationID(), time.Now().Format("20060102-150405")))
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	if err := exportLogs(f); err != nil {
+		f.Close()
+		os.Remove(out)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	slog.Info("Log diexport", "path", out)
+	return exec.Command("explorer", "/select,", out).Start()
+}
+
+// logsHandler: GET /logs?lines=200&level=warn&q=<teks> buat liat log terakhir,
+// GET /logs?format=zip buat download semua file log
+func logsHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w, r)
+	if r.Method == "OPTIONS" {
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Get("format") == "zip" {
+		var buf bytes.Buffer
+		if err := exportLogs(&buf); err != nil {
+			slog.Error("Gagal export log", "err", err)
+			writeError(w, http.StatusInternalServerError, "Gagal export log")
+			return
+		}
+		name := fmt.Sprintf("bridge-log-%s-%s.zip", stationID(), time.Now().Format("20060102-150405"))
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+		w.Write(buf.Bytes())
+		return
+	}
+
+	limit := 200
+	if v := query.Get("lines"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "Parameter lines harus angka positif")
+			return
+		}
+		limit = min(n, 5000)
+	}
+	minLevel := slog.LevelDebug
+	if v := query.Get("level"); v != "" {
+		if err := minLevel.UnmarshalText([]byte(v)); err != nil {
+			writeError(w, http.StatusBadRequest, "Parameter level tidak valid (debug, info, warn, error)")
+			return
+		}
+	}
+
+	entries, err := recentLogs(limit, minLevel, query.Get("q"))
+	if err != nil {
+		slog.Error("Gagal baca log", "err", err)
+		writeError(w, http.StatusInternalServerError, "Gagal membaca log")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    entries,
+	})
+}
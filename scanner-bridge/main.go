@@ -2,19 +2,20 @@ package main
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
-	"encoding/xml"
+	"errors"
 	"fmt"
 	"image"
 	"image/jpeg"
-	"io"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strings"
+	"strconv"
 	"time"
 
 	_ "embed"
@@ -54,121 +55,88 @@ func hideConsole() {
 // Sesuaikan path ini dengan lokasi install NAPS2 di PC lu
 const naps2Path = "C:\\Program Files\\NAPS2\\NAPS2.console.exe"
 const profileName = "Duplex ADF Scanner(K76)" // Harus sama dengan nama profile di NAPS2
+const bridgePort = ":5000"
+
+// Versi bridge, diisi waktu build: go build -ldflags "-X main.version=1.4.0"
+var version = "dev"
 
 // Struktur JSON Response
 type ScanPair struct {
 	Front string `json:"front"`          // Base64 string
 	Back  string `json:"back,omitempty"` // Base64 string
+	PageInfo
 }
 
-type Response struct {
-	Success bool       `json:"success"`
-	Data    []ScanPair `json:"data,omitempty"`
-	Message string     `json:"message,omitempty"`
-}
-type SaveRequest struct {
-	DocName    string `json:"doc_name"`
-	NPSN       string `json:"npsn"`
-	SNBapp     string `json:"sn_bapp"`
-	HasilCek   string `json:"hasil_cek"`
-	ImageFront string `json:"image_front"`
-	ImageBack  string `json:"image_back"`
+// PageInfo: info halaman selain gambarnya (hasil cek kualitas & metadata)
+type PageInfo struct {
+	FrontQuality *PageQuality `json:"front_quality,omitempty"`
+	BackQuality  *PageQuality `json:"back_quality,omitempty"`
+	FrontMeta    *PageMeta    `json:"front_meta,omitempty"`
+	BackMeta     *PageMeta    `json:"back_meta,omitempty"`
 }
 
-// Middleware manual buat CORS (biar Next.js bisa akses)
-func enableCors(w *http.ResponseWriter) {
-	(*w).Header().Set("Access-Control-Allow-Origin", "*")
-	(*w).Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-	(*w).Header().Set("Access-Control-Allow-Headers", "Content-Type")
+type Response struct {
+	Success   bool       `json:"success"`
+	SessionID string     `json:"session_id,omitempty"`
+	Data      []ScanPair `json:"data,omitempty"`
+	Message   string     `json:"message,omitempty"`
 }
 
-func scanHandler(w http.ResponseWriter, r *http.Request) {
-	enableCors(&w)
-
-	// Kalau browser kirim preflight check (OPTIONS), langsung OK in aja
-	if r.Method == "OPTIONS" {
-		return
-	}
-
-	fmt.Println("Menerima request scan...")
-
-	// 1. Buat folder sementara khusus untuk request ini
-	tempDir := filepath.Join(os.TempDir(), fmt.Sprintf("scan_session_%d", time.Now().UnixNano()))
-	if err := os.MkdirAll(tempDir, 0755); err != nil {
-		fmt.Println("Gagal buat temp dir:", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(Response{Success: false, Message: "Gagal membuat temporary directory"})
+// Middleware manual buat CORS (biar Next.js bisa akses).
+// Cuma origin yang ada di config.allowed_origins yang dikasih izin.
+func enableCors(w *http.ResponseWriter, r *http.Request) {
+	(*w).Header().Add("Vary", "Origin")
+	origin := r.Header.Get("Origin")
+	if origin == "" || !originAllowed(origin) {
 		return
 	}
-	defer os.RemoveAll(tempDir) // Hapus folder temp setelah selesai
-
-	// Ambil nama profile dari Query Param, kalau kosong pake default
-	selectedProfile := r.URL.Query().Get("profile")
-	if selectedProfile == "" {
-		selectedProfile = profileName // Default value dari konstanta
+	(*w).Header().Set("Access-Control-Allow-Origin", origin)
+	(*w).Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+	(*w).Header().Set("Access-Control-Allow-Headers", "Content-Type, "+tokenHeader)
+	// Private Network Access: Chrome nanya dulu sebelum halaman publik (HTTPS)
+	// boleh nembak localhost
+	if r.Header.Get("Access-Control-Request-Private-Network") == "true" {
+		(*w).Header().Set("Access-Control-Allow-Private-Network", "true")
 	}
+}
 
-	// 2. Siapkan command NAPS2
-	// Output pattern: $(n) akan diganti jadi urutan angka (1, 2, 3...)
-	// Contoh: scan_1.jpg, scan_2.jpg
-	outputPath := filepath.Join(tempDir, "scan_$(n).jpg")
-
-	// naps2.console.exe -o "C:\Temp\...\scan_$(n).jpg" -p "Plustek" --force
-	fmt.Printf("Scanning dengan profile: %s\n", selectedProfile)
-	cmd := exec.Command(naps2Path, "-o", outputPath, "-p", selectedProfile, "--force")
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(Response{Success: false, Message: message})
+}
 
-	// 3. Eksekusi Command
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		errMsg := fmt.Sprintf("Gagal scan: %v | Output NAPS2: %s", err, string(output))
-		fmt.Println(errMsg)
+// Opsi post-processing hasil scan
+type pipelineOptions struct {
+	Rotate bool // Putar 180 derajat
+	Duplex bool // File berurutan depan, belakang, depan, ... (false = tiap file satu sheet)
+	Deskew bool // Luruskan halaman yang miring
+	DPI    int  // Resolusi scan dari backend, dipakai kalau file gak bawa info DPI
+}
 
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(Response{Success: false, Message: errMsg})
-		return
+// processImage: Baca file -> Decode JPEG -> Rotate 180 / Deskew -> Encode JPEG
+func processImage(lg *slog.Logger, path string, opts pipelineOptions) ([]byte, error) {
+	if !opts.Rotate && !opts.Deskew {
+		// Kalau gak perlu diubah, langsung baca file aslinya
+		defer observeStep("read", time.Now())
+		return os.ReadFile(path)
 	}
 
-	// 4. Baca semua file hasil scan di folder temp
-	files, err := filepath.Glob(filepath.Join(tempDir, "scan_*.jpg"))
+	data, err := os.ReadFile(path)
 	if err != nil {
-		fmt.Println("Gagal glob files:", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(Response{Success: false, Message: "Gagal mencari file hasil scan"})
-		return
+		return nil, err
 	}
 
-	if len(files) == 0 {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(Response{Success: false, Message: "Tidak ada gambar yang dihasilkan"})
-		return
+	// Decode JPEG
+	start := time.Now()
+	img, err := decodeJPEG(data)
+	if err != nil {
+		return nil, err
 	}
+	observeStep("decode", start)
 
-	// Helper function: Baca file -> Decode JPEG -> Rotate 180 -> Encode JPEG -> Base64
-	processImage := func(path string) (string, error) {
-		// Cek apakah perlu rotate (kecuali profile SP-1120)
-		shouldRotate := !strings.Contains(selectedProfile, "SP-1120")
-
-		if !shouldRotate {
-			// Kalau gak perlu rotate, langsung baca file aslinya
-			fileBytes, err := os.ReadFile(path)
-			if err != nil {
-				return "", err
-			}
-			return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(fileBytes), nil
-		}
-
-		f, err := os.Open(path)
-		if err != nil {
-			return "", err
-		}
-		defer f.Close()
-
-		// Decode JPEG
-		img, err := jpeg.Decode(f)
-		if err != nil {
-			return "", fmt.Errorf("gagal decode jpeg: %v", err)
-		}
-
+	if opts.Rotate {
+		start := time.Now()
 		// Rotate 180 degrees
 		bounds := img.Bounds()
 		width, height := bounds.Dx(), bounds.Dy()
@@ -179,150 +147,495 @@ func scanHandler(w http.ResponseWriter, r *http.Request) {
 				newImg.Set(width-1-x, height-1-y, img.At(x, y))
 			}
 		}
+		img = newImg
+		observeStep("rotate", start)
+	}
 
-		// Encode back to JPEG
-		var buf bytes.Buffer
-		if err := jpeg.Encode(&buf, newImg, nil); err != nil {
-			return "", fmt.Errorf("gagal encode jpeg: %v", err)
+	if opts.Deskew {
+		start := time.Now()
+		var angle float64
+		img, angle = deskew(img)
+		observeStep("deskew", start)
+		if angle != 0 {
+			lg.Info("Deskew halaman", "file", filepath.Base(path), "angle", angle)
 		}
+	}
 
-		return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+	// Encode back to JPEG
+	defer observeStep("encode", time.Now())
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		return nil, fmt.Errorf("gagal encode jpeg: %v", err)
 	}
+	return buf.Bytes(), nil
+}
 
-	var scanResults []ScanPair
+// buildSheets proses file hasil scan (urutan depan, belakang, depan, ...)
+// dan nyimpen hasilnya di folder session
+func buildSheets(lg *slog.Logger, files []string, opts pipelineOptions, profile, dir string) []Sheet {
+	var sheets []Sheet
+	seq := time.Now().UnixNano()
 
-	// 5. Loop file dengan step 2 (0, 2, 4...)
-	for i := 0; i < len(files); i += 2 {
-		pair := ScanPair{}
+	type page struct {
+		path    string
+		quality *PageQuality
+		meta    *PageMeta
+	}
+	save := func(path, side string) (page, error) {
+		data, err := processImage(lg, path, opts)
+		if err != nil {
+			return page{}, err
+		}
+		// DPI dari file asli, kalau gak ada pakai resolusi dari backend
+		dpi := fileDPI(path)
+		if dpi == 0 {
+			dpi = opts.DPI
+		}
+		data = withJFIF(data, dpi)
+
+		start := time.Now()
+		meta, err := pageMeta(data, dpi, captureTime(path), profile)
+		observeStep("hash", start)
+		if err != nil {
+			return page{}, err
+		}
+		out := filepath.Join(dir, fmt.Sprintf("%d_%s.jpg", seq, side))
+		seq++
+		if err := os.WriteFile(out, data, 0644); err != nil {
+			return page{}, err
+		}
+
+		// Cek kualitas gak boleh bikin halaman gagal, cukup dicatat aja
+		start = time.Now()
+		quality, err := analyzeQuality(data, qualityThresholds())
+		observeStep("quality", start)
+		if err != nil {
+			lg.Warn("Gagal cek kualitas", "file", path, "err", err)
+		} else {
+			for _, wrn := range quality.Warnings {
+				lg.Warn("Warning kualitas halaman", "file", filepath.Base(path), "side", side, "code", wrn.Code, "detail", wrn.Message)
+			}
+		}
+		return page{out, quality, meta}, nil
+	}
+
+	step := 1
+	if opts.Duplex {
+		step = 2
+	}
+
+	// Loop file dengan step 2 (0, 2, 4...) kalau duplex
+	for i := 0; i < len(files); i += step {
+		sheet := Sheet{}
 
 		// Proses Front (i)
-		fmt.Printf("Processing Front: %s\n", files[i])
-		frontB64, err := processImage(files[i])
+		lg.Debug("Processing front", "file", files[i])
+		front, err := save(files[i], "front")
 		if err != nil {
-			fmt.Printf("Error process file %s: %v\n", files[i], err)
+			lg.Error("Gagal proses halaman", "file", files[i], "err", err)
 			continue
 		}
-		pair.Front = frontB64
+		sheet.Front, sheet.FrontQuality, sheet.FrontMeta = front.path, front.quality, front.meta
 
 		// Proses Back (i+1) jika ada
-		if i+1 < len(files) {
-			fmt.Printf("Processing Back: %s\n", files[i+1])
-			backB64, err := processImage(files[i+1])
+		if opts.Duplex && i+1 < len(files) {
+			lg.Debug("Processing back", "file", files[i+1])
+			back, err := save(files[i+1], "back")
 			if err != nil {
-				fmt.Printf("Error process file %s: %v\n", files[i+1], err)
-				// Kalau back gagal, kita biarkan kosong atau handle error
+				lg.Error("Gagal proses halaman", "file", files[i+1], "err", err)
+				// Kalau back gagal, kita biarkan kosong
 			} else {
-				pair.Back = backB64
+				sheet.Back, sheet.BackQuality, sheet.BackMeta = back.path, back.quality, back.meta
 			}
 		}
 
-		scanResults = append(scanResults, pair)
+		sheets = append(sheets, sheet)
 	}
+	return sheets
+}
 
-	// 6. Kirim Response JSON
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(Response{
-		Success: true,
-		Data:    scanResults,
-	})
+func encodeDataURI(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(data), nil
+}
 
-	fmt.Printf("Scan sukses! Mengirim %d pasang gambar (rotated 180) ke browser.\n", len(scanResults))
+// sessionPairs ngubah semua sheet di session jadi pasangan base64 (semua di
+// memory, cuma buat payload kecil kayak submit; response scan lewat writeSession)
+func sessionPairs(sheets []Sheet) ([]ScanPair, error) {
+	var pairs []ScanPair
+	for _, sh := range sheets {
+		pair := ScanPair{PageInfo: sh.PageInfo}
+		front, err := encodeDataURI(sh.Front)
+		if err != nil {
+			return nil, err
+		}
+		pair.Front = front
+		if sh.Back != "" {
+			back, err := encodeDataURI(sh.Back)
+			if err != nil {
+				return nil, err
+			}
+			pair.Back = back
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, nil
 }
 
-func profilesHandler(w http.ResponseWriter, r *http.Request) {
-	enableCors(&w)
+// Error yang udah bawa status HTTP-nya
+type httpError struct {
+	Status  int
+	Message string
+}
+
+func (e *httpError) Error() string { return e.Message }
+
+// sessionEdit: target session + mode edit dari query (?session=&mode=&sheet=)
+type sessionEdit struct {
+	Session *ScanSession // nil = bikin session baru
+	Mode    string
+	Sheet   int
+}
+
+// parseSessionEdit baca & validasi parameter edit session. Dicek sebelum
+// scan/import biar kertas (atau upload) gak kebuang percuma.
+func parseSessionEdit(query url.Values) (sessionEdit, *httpError) {
+	edit := sessionEdit{Mode: query.Get("mode")}
+	if edit.Mode == "" {
+		edit.Mode = ModeAppend
+	}
+	if v := query.Get("sheet"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return edit, &httpError{http.StatusBadRequest, "Parameter sheet harus angka"}
+		}
+		edit.Sheet = n
+	}
+
+	sessionID := query.Get("session")
+	if sessionID == "" {
+		if edit.Mode != ModeAppend {
+			return edit, &httpError{http.StatusBadRequest, "Mode insert/replace butuh parameter session"}
+		}
+		return edit, nil
+	}
+
+	edit.Session = sessions.get(sessionID)
+	if edit.Session == nil {
+		return edit, &httpError{http.StatusNotFound, "Session tidak ditemukan"}
+	}
+	sheets, _ := sessions.snapshot(sessionID)
+	if err := checkEdit(edit.Mode, edit.Sheet, len(sheets)); err != nil {
+		return edit, &httpError{http.StatusBadRequest, "Edit session tidak valid: " + err.Error()}
+	}
+	return edit, nil
+}
+
+// commitSheets proses file halaman, masukin ke session (baru kalau perlu),
+// lalu kirim isi session sebagai response. Balikin kelas error buat metrics
+// ("" kalau sukses), response error-nya udah ditulis di sini.
+func commitSheets(w http.ResponseWriter, lg *slog.Logger, edit sessionEdit, profile, source string, files []string, opts pipelineOptions) (*ScanSession, int, string) {
+	// Batch kegedean gak usah diproses sama sekali
+	if max := config.Limits.MaxPages; max > 0 && len(files) > max {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Batch berisi %d halaman, batasnya %d. Pecah jadi beberapa scan.", len(files), max))
+		return nil, 0, failLimit
+	}
+
+	sess := edit.Session
+	if sess == nil {
+		var err error
+		sess, err = sessions.create(profile, source)
+		if err != nil {
+			lg.Error("Gagal buat session", "err", err)
+			writeError(w, http.StatusInternalServerError, "Gagal membuat session scan")
+			return nil, 0, failSession
+		}
+	}
+
+	lg = lg.With("session", sess.ID)
+	added := buildSheets(lg, files, opts, profile, sess.Dir)
+	if len(added) == 0 {
+		if edit.Session == nil {
+			sessions.remove(sess.ID)
+		}
+		writeError(w, http.StatusInternalServerError, "Semua halaman gagal diproses")
+		return nil, 0, failProcessing
+	}
+	if err := sessions.apply(sess.ID, edit.Mode, edit.Sheet, added); err != nil {
+		lg.Warn("Gagal update session", "mode", edit.Mode, "sheet", edit.Sheet, "err", err)
+		for _, sh := range added {
+			removeSheetFiles(sh)
+		}
+		if edit.Session == nil {
+			sessions.remove(sess.ID)
+		}
+		var limit *limitError
+		if errors.As(err, &limit) {
+			writeError(w, http.StatusRequestEntityTooLarge, limit.Message)
+			return nil, 0, failLimit
+		}
+		writeError(w, http.StatusConflict, "Gagal update session: "+err.Error())
+		return nil, 0, failSession
+	}
+	pagesTotal.add(float64(countPages(added)), source)
+
+	writeSession(w, sess.ID)
+	return sess, len(added), ""
+}
+
+func countPages(sheets []Sheet) int {
+	n := 0
+	for _, sh := range sheets {
+		n++
+		if sh.Back != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// scanHandler: GET /scan?profile=...
+// Scan tambahan ke session yang udah ada: &session=<id>&mode=append|insert|replace&sheet=<n>
+// (sheet mulai dari 1, wajib buat insert/replace)
+func scanHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w, r)
+
+	// Kalau browser kirim preflight check (OPTIONS), langsung OK in aja
 	if r.Method == "OPTIONS" {
 		return
 	}
 
-	// Cari lokasi profiles.xml di AppData
-	appData, err := os.UserConfigDir() // Usually C:\Users\Username\AppData\Roaming
-	if err != nil {
-		fmt.Printf("Gagal get AppData: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(Response{Success: false, Message: "Gagal mendeteksi folder AppData"})
+	query := r.URL.Query()
+	job := newJobID()
+	lg := slog.With("job", job)
+	lg.Info("Menerima request scan", "session", query.Get("session"), "mode", query.Get("mode"))
+
+	ctx, ok := jobs.begin()
+	if !ok {
+		writeError(w, http.StatusServiceUnavailable, "Scanner Bridge sedang berhenti / restart, coba lagi sebentar")
+		return
+	}
+	defer jobs.end()
+
+	edit, herr := parseSessionEdit(query)
+	if herr != nil {
+		recordFailure("unknown", failInvalid)
+		writeError(w, herr.Status, herr.Message)
 		return
 	}
 
-	profilesPath := filepath.Join(appData, "NAPS2", "profiles.xml")
-	xmlFile, err := os.Open(profilesPath)
+	// Ambil nama profile dari Query Param, kalau kosong pake profile session / default
+	selectedProfile := query.Get("profile")
+	if selectedProfile == "" && edit.Session != nil {
+		selectedProfile = edit.Session.Profile
+	}
+	if selectedProfile == "" {
+		selectedProfile = profileName // Default value dari konstanta
+	}
+
+	backend := backendFor(selectedProfile)
+	lg = lg.With("profile", selectedProfile, "backend", backend.Name())
+
+	// 1. Buat folder sementara khusus untuk request ini
+	tempDir := filepath.Join(os.TempDir(), "scan_job_"+job)
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		lg.Error("Gagal buat temp dir", "err", err)
+		recordFailure(backend.Name(), failInternal)
+		writeError(w, http.StatusInternalServerError, "Gagal membuat temporary directory")
+		return
+	}
+	defer os.RemoveAll(tempDir) // Hapus folder temp setelah selesai
+
+	// 2. Scan pakai backend sesuai profile (NAPS2 / eSCL / simulator)
+	start := time.Now()
+	files, err := backend.Scan(ctx, lg, tempDir)
+	backendDuration.observe(time.Since(start).Seconds(), backend.Name())
 	if err != nil {
-		fmt.Printf("Gagal buka profiles.xml: %v\n", err)
-		// Fallback: coba cari di local AppData kalau roaming gagal, atau return empty
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(Response{Success: false, Message: "Gagal membuka file profil NAPS2"})
+		lg.Error("Scan gagal", "err", err)
+		recordFailure(backend.Name(), failBackend)
+		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	defer xmlFile.Close()
 
-	// Simple XML parsing
-	// Kita cuma butuh ambil isi tag <DisplayName>...</DisplayName>
-	// Cara paling gampang tanpa bikin struct kompleks adalah baca file sebagai text dan regex,
-	// atau decoding XML decodernya.
-	// Kita coba pake standard xml decoder dengan struct minimalis
-	type ScanProfile struct {
-		DisplayName string `xml:"DisplayName"`
+	if len(files) == 0 {
+		lg.Warn("Scan tidak menghasilkan gambar")
+		recordFailure(backend.Name(), failEmpty)
+		writeError(w, http.StatusInternalServerError, "Tidak ada gambar yang dihasilkan")
+		return
 	}
-	type ArrayOfScanProfile struct {
-		Profiles []ScanProfile `xml:"ScanProfile"`
+
+	// 3. Proses gambar, masukin ke session & kirim response (seluruh isi session)
+	sess, added, failure := commitSheets(w, lg, edit, selectedProfile, SourceScanner, files, managedPipeline(selectedProfile, backend.Pipeline()))
+	if failure != "" {
+		recordFailure(backend.Name(), failure)
+		return
 	}
+	scansTotal.inc(backend.Name(), "success")
 
-	var data ArrayOfScanProfile
-	byteValue, _ := io.ReadAll(xmlFile)
+	lg.Info("Scan sukses", "session", sess.ID, "sheets", added, "mode", edit.Mode)
+}
 
-	// Perlu import "encoding/xml" dan "io"
-	if err := xml.Unmarshal(byteValue, &data); err != nil {
-		fmt.Printf("Gagal parsing XML: %v\n", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(Response{Success: false, Message: "Gagal memparsing file profil"})
+// sessionsHandler: GET /sessions?source=hotfolder buat liat session yang ada
+// (mis. hasil hot folder yang belum diambil frontend)
+func sessionsHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w, r)
+	if r.Method == "OPTIONS" {
 		return
 	}
 
-	var profiles []string
-	for _, p := range data.Profiles {
-		if p.DisplayName != "" {
-			profiles = append(profiles, p.DisplayName)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"data":    sessions.list(r.URL.Query().Get("source")),
+	})
+}
+
+// sessionHandler: GET /session?id=... buat ambil isi session,
+// DELETE /session?id=... buat buang session
+func sessionHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w, r)
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "Parameter id wajib diisi")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeSession(w, id)
+	case http.MethodDelete:
+		if !sessions.remove(id) {
+			writeError(w, http.StatusNotFound, "Session tidak ditemukan")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Response{Success: true, Message: "Session dihapus"})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func profilesHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w, r)
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	profiles, err := profileNames()
+	if err != nil {
+		slog.Warn("Gagal baca profil NAPS2", "err", err)
+		// Kalau gak ada profile eSCL / simulator juga, gak ada yang bisa dipake sama sekali
+		if len(profiles) == 0 {
+			writeError(w, http.StatusInternalServerError, "Gagal membaca file profil NAPS2")
+			return
 		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success":  true,
-		"profiles": profiles,
+		"success":        true,
+		"profiles":       profiles,
+		"config_version": managed.version(),
 	})
 }
 
-// 1. Update dulu struct di database/db.go kamu biar cuma satu kolom path
-type ScanRecord struct {
-	ID        uint      `gorm:"primaryKey"`
-	DocName   string    `json:"doc_name"`
-	NPSN      string    `json:"npsn"`
-	SNBapp    string    `gorm:"uniqueIndex" json:"sn_bapp"`
-	HasilCek  string    `json:"hasil_cek"`
-	Path      string    `json:"path"` // <--- CUMA SATU KOLOM INI AJA
-	CreatedAt time.Time `json:"created_at"`
+// profileNames: semua profile yang bisa dipilih. Error cuma dari profiles.xml
+// NAPS2, profile eSCL / simulator tetap dikembaliin.
+func profileNames() ([]string, error) {
+	// Profile NAPS2 dari profiles.xml di AppData
+	naps2Profiles, err := readNaps2Profiles()
+
+	var profiles []string
+	for _, p := range naps2Profiles {
+		if p.DisplayName != "" {
+			profiles = append(profiles, p.DisplayName)
+		}
+	}
+	// Profile scanner jaringan (eSCL) dari config.json
+	for _, p := range config.ESCL {
+		profiles = append(profiles, p.Name)
+	}
+	// Profile simulator (development / test)
+	for _, p := range config.Simulator {
+		profiles = append(profiles, p.Name)
+	}
+	return profiles, err
 }
 
 //go:embed icon.ico
 var iconData []byte
 
 func onReady() {
+	if err := initLogging(); err != nil {
+		slog.Error("Gagal buka file log, log cuma ke console", "err", err)
+	}
+
 	systray.SetIcon(iconData)
 	systray.SetTitle("Scanner Bridge")
 	systray.SetTooltip("OWO Scanner Bridge")
 
+	mPair := systray.AddMenuItem("Tidak ada permintaan pairing", "Setujui permintaan pairing dari frontend")
+	mPair.Disable()
+	mRevoke := systray.AddMenuItem("Cabut Semua Pairing", "Frontend harus pairing ulang")
+	mCert := systray.AddMenuItem("Install Sertifikat HTTPS", "Buka sertifikat CA lokal buat diinstall ke Trusted Root")
+	mUI := systray.AddMenuItem("Buka Scanner Lokal", "Halaman scan bawaan bridge, buat kalau frontend gak bisa diakses")
+	mLogs := systray.AddMenuItem("Lihat Log", "Buka log terakhir di UI lokal")
+	mExportLogs := systray.AddMenuItem("Export Log", "Zip semua file log buat dilampirin ke tiket support")
+	systray.AddSeparator()
 	mRestart := systray.AddMenuItem("Restart", "Restart the application")
 	mConsole := systray.AddMenuItem("Hide Console", "Show/Hide the console window")
 	mQuit := systray.AddMenuItem("Exit", "Quit the whole app")
 
 	consoleVisible := true // Default visible
 
+	// Ctrl+C / console ditutup juga lewat jalur berhenti yang sama
+	go handleSignals()
+
 	// Handlers for tray menu
 	go func() {
 		for {
 			select {
+			case <-pairings.changed:
+				if req := pairings.latestPending(); req != nil {
+					mPair.SetTitle(fmt.Sprintf("Setujui Pairing %s (kode %s)", displayOrigin(req.Origin), req.Code))
+					mPair.Enable()
+				} else {
+					mPair.SetTitle("Tidak ada permintaan pairing")
+					mPair.Disable()
+				}
+			case <-mPair.ClickedCh:
+				if req := pairings.latestPending(); req != nil {
+					pairings.approve(func(p *pairRequest) bool { return p.ID == req.ID })
+				}
+			case <-mRevoke.ClickedCh:
+				pairings.revokeAll()
+			case <-mCert.ClickedCh:
+				if err := openCACert(); err != nil {
+					slog.Error("Gagal export sertifikat CA", "err", err)
+				}
+			case <-mUI.ClickedCh:
+				if err := openLocalUI(""); err != nil {
+					slog.Error("Gagal buka UI lokal", "err", err)
+				}
+			case <-mLogs.ClickedCh:
+				if err := openLocalUI("logs"); err != nil {
+					slog.Error("Gagal buka UI lokal", "err", err)
+				}
+			case <-mExportLogs.ClickedCh:
+				if err := exportLogsToFile(); err != nil {
+					slog.Error("Gagal export log", "err", err)
+				}
 			case <-mQuit.ClickedCh:
-				systray.Quit()
+				mRestart.Disable()
+				mQuit.Disable()
+				go exitBridge("exit")
 			case <-mConsole.ClickedCh:
 				if consoleVisible {
 					hideConsole()
@@ -334,47 +647,117 @@ func onReady() {
 					consoleVisible = true
 				}
 			case <-mRestart.ClickedCh:
-				fmt.Println("Restarting...")
-				exe, err := os.Executable()
-				if err != nil {
-					fmt.Printf("Failed to get executable path: %v\n", err)
-					continue
-				}
-				cmd := exec.Command(exe, os.Args[1:]...)
-				// Detach process to ensure clean restart
-				cmd.Stdin = os.Stdin
-				cmd.Stdout = os.Stdout
-				cmd.Stderr = os.Stderr
-				if err := cmd.Start(); err != nil {
-					fmt.Printf("Failed to restart: %v\n", err)
-				} else {
-					systray.Quit()
-				}
+				slog.Info("Restarting...")
+				mRestart.Disable()
+				mQuit.Disable()
+				go func() {
+					if err := restartBridge(); err != nil {
+						slog.Error("Failed to get executable path", "err", err)
+						mRestart.Enable()
+						mQuit.Enable()
+					}
+				}()
 			}
 		}
 	}()
 
+	if err := loadConfig(); err != nil {
+		slog.Error("Gagal baca config, pakai default", "err", err)
+	}
+	applyLogConfig(config.Log)
+	if err := pairings.load(); err != nil {
+		slog.Error("Gagal baca data pairing", "err", err)
+	}
+	go pairings.consoleApprover()
+
+	// Bersihin session sisa run sebelumnya, terus jalanin janitor
+	sessions.reset()
+	go sessions.janitor(sessionTTL)
+
+	if config.HotFolder.Enabled {
+		if err := startHotFolder(config.HotFolder); err != nil {
+			slog.Error("Hot folder gagal dijalankan", "err", err)
+		}
+	}
+
+	// Outbox buat submission yang gagal kirim ke server db
+	if err := outbox.init(); err != nil {
+		slog.Error("Gagal siapin outbox", "err", err)
+	} else {
+		go outbox.worker()
+	}
+
+	// Profile & aturan terpusat dari service db, lalu lapor lewat heartbeat
+	go managed.syncLoop()
+	go heartbeatLoop()
+
 	// Start Server
 	go func() {
-		http.HandleFunc("/scan", scanHandler)
-		http.HandleFunc("/profiles", profilesHandler)
-
-		port := ":5000"
-		fmt.Printf("Scanner Bridge (Golang) siap di http://localhost%s\n", port)
+		http.HandleFunc("/", rootHandler)
+		http.Handle(uiPath, uiHandler())
+		http.HandleFunc("/ca.crt", caCertHandler)
+		http.HandleFunc("/pair", pairHandler)
+		http.HandleFunc("/scan", requirePairing(scanHandler))
+		http.HandleFunc("/import", requirePairing(importHandler))
+		http.HandleFunc("/session", requirePairing(sessionHandler))
+		http.HandleFunc("/sessions", requirePairing(sessionsHandler))
+		http.HandleFunc("/submit", requirePairing(submitHandler))
+		http.HandleFunc("/outbox", requirePairing(outboxHandler))
+		http.HandleFunc("/profiles", requirePairing(profilesHandler))
+		http.HandleFunc("/logs", requirePairing(logsHandler))
+		http.HandleFunc("/metrics", metricsHandler)
+		http.HandleFunc("/health", healthHandler)
+
+		if config.TLS {
+			go serveTLS()
+		}
 
-		if err := http.ListenAndServe(port, nil); err != nil {
-			log.Printf("Gagal menjalankan server: %v", err)
-			systray.Quit()
+		srv := &http.Server{Addr: bridgePort}
+		err := serve(srv, func(ln net.Listener) error {
+			slog.Info("Scanner Bridge (Golang) siap", "url", "http://localhost"+bridgePort, "ui", "http://localhost"+bridgePort+uiPath)
+			return srv.Serve(ln)
+		})
+		if err != nil {
+			// Tray sengaja gak ditutup biar operator bisa Restart setelah program lain ditutup
+			msg := portConflictMessage(err)
+			slog.Error("Gagal menjalankan server", "err", err, "detail", msg)
+			systray.SetTooltip("OWO Scanner Bridge - gagal jalan, port " + bridgePort + " bentrok")
+			showMessage(msg, mbIconError)
 		}
 	}()
 }
 
+// serveTLS jalanin listener HTTPS di samping HTTP biasa
+func serveTLS() {
+	cert, err := loadTLSCertificate()
+	if err != nil {
+		slog.Error("HTTPS gak bisa jalan", "err", err)
+		return
+	}
+
+	server := &http.Server{
+		Addr:      config.TLSPort,
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12},
+	}
+	err = serve(server, func(ln net.Listener) error {
+		slog.Info("Scanner Bridge HTTPS siap", "url", "https://localhost"+config.TLSPort)
+		return server.ServeTLS(ln, "", "")
+	})
+	if err != nil {
+		slog.Error("Gagal menjalankan server HTTPS", "err", err)
+	}
+}
+
 func onExit() {
-	// Clean up here if needed
-	fmt.Println("Exiting Scanner Bridge...")
+	// Kalau keluar bukan lewat menu tray (mis. systray error), tetap berhenti rapi
+	stopBridge("exit")
+	slog.Info("Exiting Scanner Bridge...")
 	os.Exit(0)
 }
 
 func main() {
+	if !acquireInstance() {
+		return
+	}
 	systray.Run(onReady, onExit)
 }
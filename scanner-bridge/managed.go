@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"dbclient"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Profile terpusat: definisi profile scan + aturan post-processing disimpen
+// di service db (GET /profiles/config). Bridge narik waktu start dan berkala,
+// nimpa setting di profiles.xml NAPS2 biar gak drift, dan lapor versinya
+// lewat heartbeat. Cache terakhir disimpen di managed.json biar tetap
+// berlaku walau server db lagi gak bisa dihubungi.
+
+const defaultProfileSync = 15 * time.Minute
+
+// Definisi profile terpusat dari paket dbclient (sama persis dengan service db)
+type (
+	ManagedProfile = dbclient.ManagedProfile
+	ManagedConfig  = dbclient.ProfileConfig
+)
+
+type managedStore struct {
+	mu  sync.RWMutex
+	cfg ManagedConfig
+}
+
+var managed = &managedStore{}
+
+func (m *managedStore) get() ManagedConfig {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.cfg
+}
+
+func (m *managedStore) set(cfg ManagedConfig) {
+	m.mu.Lock()
+	m.cfg = cfg
+	m.mu.Unlock()
+}
+
+func (m *managedStore) version() int {
+	return m.get().Version
+}
+
+func (m *managedStore) profile(name string) (ManagedProfile, bool) {
+	for _, p := range m.get().Profiles {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return ManagedProfile{}, false
+}
+
+// managedPipeline nimpa opsi bawaan backend pakai aturan terpusat (kalau ada)
+func managedPipeline(profile string, opts pipelineOptions) pipelineOptions {
+	p, ok := managed.profile(profile)
+	if !ok {
+		return opts
+	}
+	if p.Rotate != nil {
+		opts.Rotate = *p.Rotate
+	}
+	if p.Deskew != nil {
+		opts.Deskew = *p.Deskew
+	}
+	return opts
+}
+
+// qualityThresholds: batas cek kualitas terpusat kalau ada, selain itu config.json
+func qualityThresholds() QualityThresholds {
+	if q := managed.get().Quality; q != nil {
+		return QualityThresholds(*q)
+	}
+	return config.Quality
+}
+
+func profileSyncInterval() time.Duration {
+	if config.ProfileSyncMinutes > 0 {
+		return time.Duration(config.ProfileSyncMinutes) * time.Minute
+	}
+	return defaultProfileSync
+}
+
+func managedCachePath() (string, error) {
+	dir, err := appDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "managed.json"), nil
+}
+
+// load baca cache managed.json dari sync terakhir
+func (m *managedStore) load() error {
+	path, err := managedCachePath()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var cfg ManagedConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("managed.json rusak: %v", err)
+	}
+	m.set(cfg)
+	return nil
+}
+
+func (m *managedStore) save(cfg ManagedConfig) error {
+	path, err := managedCachePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// syncLoop: apply cache dulu (offline tetap jalan), lalu tarik dari server berkala.
+// profiles.xml di-apply ulang tiap putaran walau versinya sama, soalnya
+// operator bisa aja ngubah profile lewat GUI NAPS2.
+func (m *managedStore) syncLoop() {
+	if err := m.load(); err != nil {
+		slog.Error("Gagal baca cache profile terpusat", "err", err)
+	}
+	m.applyNaps2()
+
+	failing := false
+	for {
+		err := m.sync()
+		switch {
+		case err != nil && !failing:
+			slog.Warn("Gagal tarik profile terpusat dari server db", "err", err)
+			failing = true
+		case err == nil && failing:
+			slog.Info("Tarik profile terpusat jalan lagi")
+			failing = false
+		}
+		m.applyNaps2()
+		time.Sleep(profileSyncInterval())
+	}
+}
+
+func (m *managedStore) sync() error {
+	cfg, err := fetchManagedConfig()
+	if err != nil {
+		return err
+	}
+	// Bandingin isinya, bukan cuma nomor versi: config di server bisa aja
+	// di-reset / di-restore ke versi yang nomornya lebih kecil atau sama
+	if sameConfig(cfg, m.get()) {
+		return nil
+	}
+	slog.Info("Profile terpusat berubah", "from", m.version(), "to", cfg.Version, "profiles", len(cfg.Profiles))
+	m.set(cfg)
+	return m.save(cfg)
+}
+
+func sameConfig(a, b ManagedConfig) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+func fetchManagedConfig() (ManagedConfig, error) {
+	cfg, err := dbAPI(heartbeatClient).ProfileConfig(context.Background(), 0)
+	if err != nil {
+		return ManagedConfig{}, err
+	}
+	return *cfg, nil
+}
+
+// applyNaps2 nimpa profiles.xml NAPS2 sesuai profile terpusat
+func (m *managedStore) applyNaps2() {
+	cfg := m.get()
+	if len(cfg.Profiles) == 0 {
+		return
+	}
+	path, err := naps2ProfilesPath()
+	if err != nil {
+		slog.Error("Gagal mendeteksi folder AppData", "err", err)
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		slog.Warn("Profile terpusat gak bisa di-apply, profiles.xml NAPS2 gak kebaca", "err", err)
+		return
+	}
+
+	out, changes, err := rewriteNaps2Profiles(data, cfg.Profiles)
+	if err != nil {
+		slog.Error("Gagal apply profile terpusat ke profiles.xml", "err", err)
+		return
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	// Backup dulu, terus tulis lewat file temp biar NAPS2 gak kebaca setengah jadi
+	if err := os.WriteFile(path+".bak", data, 0644); err != nil {
+		slog.Error("Gagal backup profiles.xml", "err", err)
+		return
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, out, 0644); err != nil {
+		slog.Error("Gagal nulis profiles.xml", "err", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		slog.Error("Gagal nulis profiles.xml", "err", err)
+		return
+	}
+	slog.Info("Profile NAPS2 disamakan dengan profile terpusat", "version", cfg.Version, "changes", changes)
+}
+
+var (
+	scanProfileBlock = regexp.MustCompile(`(?s)<ScanProfile>.*?</ScanProfile>`)
+	// Elemen profiles.xml yang diubah setXMLTag
+	naps2Tags = xmlTagPatterns("DisplayName", "IsDefault", "Resolution", "PaperSource", "BitDepth", "PageSize")
+)
+
+func xmlTagPatterns(tags ...string) map[string]*regexp.Regexp {
+	m := make(map[string]*regexp.Regexp, len(tags))
+	for _, tag := range tags {
+		m[tag] = regexp.MustCompile(`<` + tag + `(?:\s[^>]*)?(?:/>|>[^<]*</` + tag + `>)`)
+	}
+	return m
+}
+
+// rewriteNaps2Profiles ngedit profiles.xml per teks (bukan unmarshal/marshal)
+// biar elemen lain & atribut xsi:nil tetap utuh. Profile terpusat yang belum
+// ada di-clone dari profile default (device-nya ikut profile default itu).
+func rewriteNaps2Profiles(data []byte, profiles []ManagedProfile) ([]byte, []string, error) {
+	src := string(data)
+	locs := scanProfileBlock.FindAllStringIndex(src, -1)
+	if len(locs) == 0 {
+		return nil, nil, errors.New("profiles.xml gak punya ScanProfile sama sekali, bikin minimal satu profile di NAPS2 dulu")
+	}
+
+	type block struct {
+		name, text string
+	}
+	blocks := make([]block, len(locs))
+	template := 0
+	for i, loc := range locs {
+		var p struct {
+			DisplayName string `xml:"DisplayName"`
+			IsDefault   bool   `xml:"IsDefault"`
+		}
+		text := src[loc[0]:loc[1]]
+		if err := xml.Unmarshal([]byte(text), &p); err != nil {
+			return nil, nil, fmt.Errorf("gagal memparsing profile ke-%d: %v", i+1, err)
+		}
+		blocks[i] = block{name: p.DisplayName, text: text}
+		if p.IsDefault {
+			template = i
+		}
+	}
+
+	var changes []string
+	var added []string
+	for _, mp := range profiles {
+		idx := -1
+		for i, b := range blocks {
+			if b.name == mp.Name {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			text := setXMLTag(blocks[template].text, "DisplayName", mp.Name)
+			text = setXMLTag(text, "IsDefault", "false")
+			text = applyManagedProfile(text, mp)
+			added = append(added, text)
+			changes = append(changes, mp.Name+": profile baru")
+			continue
+		}
+		text := applyManagedProfile(blocks[idx].text, mp)
+		if text != blocks[idx].text {
+			blocks[idx].text = text
+			changes = append(changes, mp.Name)
+		}
+	}
+	if len(changes) == 0 {
+		return data, nil, nil
+	}
+
+	var b strings.Builder
+	last := 0
+	for i, loc := range locs {
+		b.WriteString(src[last:loc[0]])
+		b.WriteString(blocks[i].text)
+		last = loc[1]
+	}
+	// Profile baru ditaruh setelah profile terakhir, ngikut gaya newline file-nya
+	nl := "\n"
+	if strings.Contains(src, "\r\n") {
+		nl = "\r\n"
+	}
+	for _, text := range added {
+		b.WriteString(nl + "  " + text)
+	}
+	b.WriteString(src[last:])
+	return []byte(b.String()), changes, nil
+}
+
+func applyManagedProfile(text string, mp ManagedProfile) string {
+	if mp.DPI > 0 {
+		text = setXMLTag(text, "Resolution", "Dpi"+strconv.Itoa(mp.DPI))
+	}
+	if mp.PaperSource != "" {
+		text = setXMLTag(text, "PaperSource", mp.PaperSource)
+	}
+	if mp.BitDepth != "" {
+		text = setXMLTag(text, "BitDepth", mp.BitDepth)
+	}
+	if mp.PageSize != "" {
+		text = setXMLTag(text, "PageSize", mp.PageSize)
+	}
+	return text
+}
+
+// setXMLTag ganti isi elemen <tag>...</tag> (atau <tag /> kosong) yang pertama.
+// Elemen yang gak ada dibiarin, NAPS2 sensitif sama urutan elemen. tag harus
+// ada di naps2Tags.
+func setXMLTag(text, tag, value string) string {
+	loc := naps2Tags[tag].FindStringIndex(text)
+	if loc == nil {
+		return text
+	}
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(value))
+	return text[:loc[0]] + "<" + tag + ">" + escaped.String() + "</" + tag + ">" + text[loc[1]:]
+}
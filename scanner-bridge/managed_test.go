@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// managedServer: service db palsu yang balikin config profile dari *cfg
+func managedServer(t *testing.T, cfg *ManagedConfig) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": cfg})
+	}))
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("AppData", dir)
+	oldURL, oldCfg := config.DBAPIURL, managed.get()
+	config.DBAPIURL = srv.URL
+	t.Cleanup(func() { config.DBAPIURL = oldURL; managed.set(oldCfg) })
+}
+
+func TestManagedSyncRestoredVersion(t *testing.T) {
+	server := ManagedConfig{Version: 7}
+	server.Profiles = []ManagedProfile{{Name: "Scan BAPP", DPI: 300}}
+	managedServer(t, &server)
+	managed.set(ManagedConfig{})
+
+	if err := managed.sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := managed.get(); got.Version != 7 || got.Profiles[0].DPI != 300 {
+		t.Fatalf("setelah sync: %+v", got)
+	}
+
+	// Config di server di-restore ke versi lama, isinya beda
+	server = ManagedConfig{Version: 3}
+	server.Profiles = []ManagedProfile{{Name: "Scan BAPP", DPI: 200}}
+	if err := managed.sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := managed.get(); got.Version != 3 || got.Profiles[0].DPI != 200 {
+		t.Errorf("versi lebih kecil gak di-apply: %+v", got)
+	}
+
+	// Nomor versi sama tapi isi beda (mis. database di-reset) tetap di-apply
+	server.Profiles = []ManagedProfile{{Name: "Scan BAPP", DPI: 600}}
+	managed.sync()
+	if got := managed.get(); got.Profiles[0].DPI != 600 {
+		t.Errorf("isi beda dengan versi sama gak di-apply: %+v", got)
+	}
+}
+
+func TestSetXMLTag(t *testing.T) {
+	text := `<ScanProfile><DisplayName>Lama</DisplayName><IsDefault /><Resolution>Dpi200</Resolution></ScanProfile>`
+	text = setXMLTag(text, "DisplayName", "A & B")
+	text = setXMLTag(text, "IsDefault", "true")
+	text = setXMLTag(text, "PageSize", "A4") // Gak ada, dibiarin
+	want := `<ScanProfile><DisplayName>A &amp; B</DisplayName><IsDefault>true</IsDefault><Resolution>Dpi200</Resolution></ScanProfile>`
+	if text != want {
+		t.Errorf("hasil:\n%s\nharusnya:\n%s", text, want)
+	}
+	if strings.Contains(text, "PageSize") {
+		t.Error("elemen yang gak ada gak boleh ditambahin")
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"dbclient"
+	"encoding/binary"
+	"encoding/hex"
+	"image/jpeg"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Metadata per halaman, biar service db bisa nyatet & ngecek asal file:
+// hash dihitung dari byte JPEG yang persis dikirim (isi data URI).
+type PageMeta = dbclient.PageMeta
+
+func pageMeta(data []byte, dpi int, capturedAt time.Time, profile string) (*PageMeta, error) {
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return &PageMeta{
+		SHA256:     hex.EncodeToString(sum[:]),
+		Width:      cfg.Width,
+		Height:     cfg.Height,
+		DPI:        dpi,
+		Bytes:      len(data),
+		Format:     http.DetectContentType(data),
+		CapturedAt: capturedAt,
+		StationID:  stationID(),
+		Profile:    profile,
+	}, nil
+}
+
+// captureTime: waktu file ditulis scanner / MFP, fallback ke sekarang
+func captureTime(path string) time.Time {
+	if info, err := os.Stat(path); err == nil {
+		return info.ModTime()
+	}
+	return time.Now()
+}
+
+// jfifSegment: isi segmen APP0 JFIF (tanpa marker & length), nil kalau gak ada
+func jfifSegment(data []byte) []byte {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil
+	}
+	i := 2
+	for i+4 <= len(data) && data[i] == 0xFF {
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker == 0xDA || length < 2 || i+2+length > len(data) {
+			break // Udah masuk data gambar
+		}
+		seg := data[i+4 : i+2+length]
+		if marker == 0xE0 && len(seg) >= 12 && string(seg[:5]) == "JFIF\x00" {
+			return seg
+		}
+		i += 2 + length
+	}
+	return nil
+}
+
+// jpegDPI baca density dari JFIF. 0 kalau gak ada / satuannya bukan inch atau cm.
+func jpegDPI(data []byte) int {
+	seg := jfifSegment(data)
+	if seg == nil {
+		return 0
+	}
+	switch seg[7] {
+	case 1: // dots per inch
+		return int(binary.BigEndian.Uint16(seg[8:]))
+	case 2: // dots per cm
+		return int(float64(binary.BigEndian.Uint16(seg[8:]))*2.54 + 0.5)
+	}
+	return 0
+}
+
+// withJFIF nyelipin segmen JFIF berisi DPI. Encoder jpeg Go gak nulis APP0,
+// jadi info resolusi hilang tiap kali halaman di-rotate / deskew.
+func withJFIF(data []byte, dpi int) []byte {
+	if dpi <= 0 || dpi > 0xFFFF || len(data) < 2 || jfifSegment(data) != nil {
+		return data
+	}
+	app0 := []byte{0xFF, 0xE0, 0x00, 0x10, 'J', 'F', 'I', 'F', 0x00, 0x01, 0x01, 0x01, 0, 0, 0, 0, 0x00, 0x00}
+	binary.BigEndian.PutUint16(app0[12:], uint16(dpi))
+	binary.BigEndian.PutUint16(app0[14:], uint16(dpi))
+
+	out := make([]byte, 0, len(data)+len(app0))
+	out = append(out, data[:2]...) // SOI
+	out = append(out, app0...)
+	return append(out, data[2:]...)
+}
+
+// fileDPI: DPI dari header file JPEG asli (cukup baca bagian awal file)
+func fileDPI(path string) int {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	head := make([]byte, 64<<10)
+	n, _ := io.ReadFull(f, head)
+	return jpegDPI(head[:n])
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics format teks Prometheus di GET /metrics (tanpa pairing biar bisa
+// di-scrape dari server monitoring, isinya cuma angka). Sengaja gak pakai
+// client_golang, cukup counter & histogram sederhana di bawah.
+
+// Kelas error buat bridge_scan_failures_total
+const (
+	failBackend    = "backend"    // NAPS2 / eSCL / simulator gagal
+	failEmpty      = "empty"      // Backend sukses tapi gak ada halaman
+	failInvalid    = "invalid"    // Request / upload gak valid
+	failProcessing = "processing" // Decode / rotate / encode halaman gagal
+	failSession    = "session"    // Gagal bikin / update session
+	failLimit      = "limit"      // Kena batas halaman / ukuran
+	failInternal   = "internal"   // Temp dir / file system
+)
+
+var (
+	scansTotal = newCounterVec("bridge_scans_total",
+		"Jumlah job scan/import per backend dan hasil.", "backend", "result")
+	pagesTotal = newCounterVec("bridge_pages_total",
+		"Jumlah halaman yang berhasil diproses per sumber.", "source")
+	scanFailures = newCounterVec("bridge_scan_failures_total",
+		"Jumlah job scan/import yang gagal per kelas error.", "backend", "class")
+	backendDuration = newHistogramVec("bridge_backend_duration_seconds",
+		"Lama backend scan (NAPS2 / eSCL / simulator) sampai semua halaman jadi.",
+		[]float64{1, 2.5, 5, 10, 20, 30, 60, 120, 300, 600}, "backend")
+	processingDuration = newHistogramVec("bridge_processing_duration_seconds",
+		"Lama tiap langkah pemrosesan per halaman.",
+		[]float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}, "step")
+	responseBytes = newHistogramVec("bridge_response_bytes",
+		"Ukuran response session (JSON + base64) yang dikirim ke client.",
+		[]float64{256 << 10, 1 << 20, 4 << 20, 16 << 20, 64 << 20, 128 << 20, 256 << 20, 512 << 20})
+)
+
+var metricsRegistry = []metricWriter{scansTotal, pagesTotal, scanFailures, backendDuration, processingDuration, responseBytes}
+
+type metricWriter interface {
+	write(w io.Writer)
+}
+
+// recordFailure: catat job gagal (bridge_scans_total result=failure + kelasnya)
+func recordFailure(backend, class string) {
+	scansTotal.inc(backend, "failure")
+	scanFailures.inc(backend, class)
+}
+
+// observeStep: defer observeStep("deskew", time.Now())
+func observeStep(step string, start time.Time) {
+	processingDuration.observe(time.Since(start).Seconds(), step)
+}
+
+// metricsHandler: GET /metrics
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	for _, m := range metricsRegistry {
+		m.write(bw)
+	}
+	bw.Flush()
+}
+
+// ---------- Counter ----------
+
+type counterVec struct {
+	name, help string
+	labels     []string
+	mu         sync.Mutex
+	values     map[string]float64
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, labels: labels, values: make(map[string]float64)}
+}
+
+func (c *counterVec) inc(values ...string) {
+	c.add(1, values...)
+}
+
+func (c *counterVec) add(v float64, values ...string) {
+	key := labelKey(values)
+	c.mu.Lock()
+	c.values[key] += v
+	c.mu.Unlock()
+}
+
+func (c *counterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, key, ""), formatFloat(c.values[key]))
+	}
+}
+
+// ---------- Histogram ----------
+
+type histogram struct {
+	counts []uint64 // Per bucket (belum kumulatif)
+	sum    float64
+	count  uint64
+}
+
+type histogramVec struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+	mu         sync.Mutex
+	series     map[string]*histogram
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	return &histogramVec{name: name, help: help, labels: labels, buckets: buckets, series: make(map[string]*histogram)}
+}
+
+func (h *histogramVec) observe(v float64, values ...string) {
+	key := labelKey(values)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *histogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		var cumulative uint64
+		for i, le := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, key, formatFloat(le)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, key, "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, key, ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, key, ""), s.count)
+	}
+}
+
+// ---------- Helper ----------
+
+const labelSep = "\xff"
+
+func labelKey(values []string) string {
+	return strings.Join(values, labelSep)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatLabels: {a="x",b="y"} plus le kalau histogram bucket
+func formatLabels(names []string, key, le string) string {
+	var parts []string
+	if len(names) > 0 {
+		for i, v := range strings.Split(key, labelSep) {
+			if i < len(names) {
+				parts = append(parts, names[i]+`="`+escapeLabel(v)+`"`)
+			}
+		}
+	}
+	if le != "" {
+		parts = append(parts, `le="`+le+`"`)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+		return strconv.FormatFloat(v, 'f', -1, 64) // Byte gak usah pakai eksponen
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,486 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"dbclient"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outbox: kalau server db lagi down, submission disimpen dulu di disk
+// lalu dikirim ulang di background dengan backoff.
+
+const (
+	outboxMinBackoff = 30 * time.Second
+	outboxMaxBackoff = 30 * time.Minute
+	outboxPoll       = 10 * time.Second
+)
+
+func dbAPIURL() string {
+	return strings.TrimRight(config.DBAPIURL, "/")
+}
+
+var dbClient = &http.Client{Timeout: 2 * time.Minute}
+
+// dbAPI: client service db pakai db_api_url dari config.json
+func dbAPI(hc *http.Client) *dbclient.Client {
+	return dbclient.New(dbAPIURL(), hc)
+}
+
+// Status item outbox
+const (
+	OutboxPending = "pending" // Nunggu dikirim (ulang)
+	OutboxFailed  = "failed"  // Ditolak server (4xx), gak dicoba lagi otomatis
+)
+
+type OutboxItem struct {
+	ID          string               `json:"id"`
+	Status      string               `json:"status"`
+	Attempts    int                  `json:"attempts"`
+	LastError   string               `json:"last_error,omitempty"`
+	NextAttempt time.Time            `json:"next_attempt"`
+	CreatedAt   time.Time            `json:"created_at"`
+	Request     dbclient.SaveRequest `json:"request"`
+}
+
+// Ringkasan buat endpoint /outbox (tanpa gambar base64 yang gede)
+type OutboxSummary struct {
+	ID          string    `json:"id"`
+	Status      string    `json:"status"`
+	NPSN        string    `json:"npsn"`
+	SNBapp      string    `json:"sn_bapp"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error,omitempty"`
+	NextAttempt time.Time `json:"next_attempt"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type outboxStore struct {
+	mu   sync.Mutex
+	dir  string
+	wake chan struct{}
+}
+
+var outbox = &outboxStore{wake: make(chan struct{}, 1)}
+
+func (o *outboxStore) init() error {
+	dir, err := appDir()
+	if err != nil {
+		return err
+	}
+	o.dir = filepath.Join(dir, "outbox")
+	return os.MkdirAll(o.dir, 0755)
+}
+
+func (o *outboxStore) path(id string) string {
+	return filepath.Join(o.dir, id+".json")
+}
+
+// write nyimpen item secara atomic (tulis ke .tmp lalu rename)
+func (o *outboxStore) write(item *OutboxItem) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	tmp := o.path(item.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, o.path(item.ID))
+}
+
+func (o *outboxStore) read(id string) (*OutboxItem, error) {
+	data, err := os.ReadFile(o.path(id))
+	if err != nil {
+		return nil, err
+	}
+	var item OutboxItem
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+var errOutboxDisabled = errors.New("outbox tidak aktif")
+
+// Batas panjang station ID di submission ID, kolom di service db 128 karakter
+const maxSubmissionStation = 64
+
+// newSubmissionID: ID unik per submission ke service db, station ID + 16
+// byte acak biar gak bisa tabrakan dengan station lain
+func newSubmissionID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// crypto/rand gak pernah gagal di OS yang didukung
+		panic(err)
+	}
+	station := stationID()
+	if len(station) > maxSubmissionStation {
+		station = station[:maxSubmissionStation]
+	}
+	return station + "-" + hex.EncodeToString(b[:])
+}
+
+func (o *outboxStore) enqueue(req dbclient.SaveRequest, lastErr error) (*OutboxItem, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.dir == "" {
+		return nil, errOutboxDisabled
+	}
+
+	// Pakai SubmissionID kiriman pertama biar retry gak bikin record dobel
+	// kalau sebenarnya server udah sempat nyimpen
+	if req.SubmissionID == "" {
+		req.SubmissionID = newSubmissionID()
+	}
+	now := time.Now()
+	item := &OutboxItem{
+		ID:          fmt.Sprintf("%d", now.UnixNano()),
+		Status:      OutboxPending,
+		Attempts:    1,
+		NextAttempt: now.Add(outboxMinBackoff),
+		CreatedAt:   now,
+		Request:     req,
+	}
+	if lastErr != nil {
+		item.LastError = lastErr.Error()
+	}
+	if err := o.write(item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (o *outboxStore) list() ([]OutboxItem, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.dir == "" {
+		return nil, errOutboxDisabled
+	}
+
+	files, err := filepath.Glob(filepath.Join(o.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var items []OutboxItem
+	for _, f := range files {
+		item, err := o.read(strings.TrimSuffix(filepath.Base(f), ".json"))
+		if err != nil {
+			slog.Warn("Outbox: gagal baca item", "file", f, "err", err)
+			continue
+		}
+		items = append(items, *item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].CreatedAt.Before(items[j].CreatedAt) })
+	return items, nil
+}
+
+// retryNow ngereset jadwal item (termasuk yang failed) biar langsung dicoba lagi
+func (o *outboxStore) retryNow(id string) error {
+	o.mu.Lock()
+	item, err := o.read(id)
+	if err == nil {
+		item.Status = OutboxPending
+		item.NextAttempt = time.Now()
+		err = o.write(item)
+	}
+	o.mu.Unlock()
+
+	if err == nil {
+		o.kick()
+	}
+	return err
+}
+
+func (o *outboxStore) remove(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return os.Remove(o.path(id))
+}
+
+func (o *outboxStore) kick() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+func backoff(attempts int) time.Duration {
+	d := outboxMinBackoff
+	for i := 1; i < attempts && d < outboxMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > outboxMaxBackoff {
+		d = outboxMaxBackoff
+	}
+	return d
+}
+
+// worker ngirim item pending yang udah waktunya, satu per satu
+func (o *outboxStore) worker() {
+	ticker := time.NewTicker(outboxPoll)
+	defer ticker.Stop()
+	for {
+		items, err := o.list()
+		if err != nil {
+			slog.Error("Outbox: gagal list item", "err", err)
+		}
+		for _, item := range items {
+			if item.Status != OutboxPending || time.Now().Before(item.NextAttempt) {
+				continue
+			}
+			ctx, ok := jobs.begin()
+			if !ok {
+				return // Bridge lagi berhenti, item tetap di disk
+			}
+			o.deliver(ctx, item)
+			jobs.end()
+		}
+
+		select {
+		case <-ticker.C:
+		case <-o.wake:
+		}
+	}
+}
+
+func (o *outboxStore) deliver(ctx context.Context, item OutboxItem) {
+	// Item dari versi lama belum punya SubmissionID, disimpen dulu biar
+	// retry berikutnya pakai ID yang sama
+	if item.Request.SubmissionID == "" {
+		item.Request.SubmissionID = newSubmissionID()
+		o.mu.Lock()
+		_, err := os.Stat(o.path(item.ID)) // Jangan hidupin lagi item yang udah dihapus
+		if err == nil {
+			err = o.write(&item)
+		}
+		o.mu.Unlock()
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				slog.Error("Outbox: gagal update item", "outbox", item.ID, "err", err)
+			}
+			return
+		}
+	}
+	err := dbAPI(dbClient).Save(ctx, item.Request)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	// Bisa aja item udah dihapus operator waktu lagi dikirim
+	if _, statErr := os.Stat(o.path(item.ID)); statErr != nil {
+		return
+	}
+
+	if err == nil {
+		slog.Info("Outbox: item terkirim", "outbox", item.ID, "npsn", item.Request.NPSN, "attempts", item.Attempts+1)
+		os.Remove(o.path(item.ID))
+		return
+	}
+
+	item.Attempts++
+	item.LastError = err.Error()
+	if dbclient.IsRejected(err) {
+		item.Status = OutboxFailed
+	} else {
+		item.NextAttempt = time.Now().Add(backoff(item.Attempts))
+	}
+	slog.Warn("Outbox: gagal kirim", "outbox", item.ID, "attempts", item.Attempts, "status", item.Status, "err", err)
+	if err := o.write(&item); err != nil {
+		slog.Error("Outbox: gagal update item", "outbox", item.ID, "err", err)
+	}
+}
+
+type SubmitRequest struct {
+	SessionID string `json:"session_id"`
+	DocName   string `json:"doc_name"`
+	NPSN      string `json:"npsn"`
+	SNBapp    string `json:"sn_bapp"`
+	HasilCek  string `json:"hasil_cek"`
+	Kode      string `json:"kode"`
+	Mode      string `json:"mode"` // "new_version" = simpan sebagai versi baru dokumen yang udah ada
+}
+
+// savePages: semua sheet session jadi halaman PDF, depan lalu belakang per lembar
+func savePages(pairs []ScanPair) []dbclient.Page {
+	var pages []dbclient.Page
+	for i, p := range pairs {
+		pages = append(pages, dbclient.Page{Image: p.Front, Sheet: i + 1, Side: dbclient.PageSideFront, Meta: p.FrontMeta})
+		if p.Back != "" {
+			pages = append(pages, dbclient.Page{Image: p.Back, Sheet: i + 1, Side: dbclient.PageSideBack, Meta: p.BackMeta})
+		}
+	}
+	return pages
+}
+
+// submitHandler: POST /submit, kirim session langsung ke service db.
+// Kalau server gak bisa dihubungi, masuk outbox dan session dilepas.
+func submitHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w, r)
+	if r.Method == "OPTIONS" {
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req SubmitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Request tidak valid")
+		return
+	}
+	if req.SessionID == "" {
+		writeError(w, http.StatusBadRequest, "session_id wajib diisi")
+		return
+	}
+	// Dicek di sini juga biar data salah gak sempat masuk outbox
+	npsn, err := dbclient.ParseNPSN(req.NPSN)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	sn, err := dbclient.ParseSNBapp(req.SNBapp)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.NPSN, req.SNBapp = string(npsn), string(sn)
+
+	sheets, release, ok := sessions.borrow(req.SessionID)
+	if !ok {
+		writeError(w, http.StatusNotFound, "Session tidak ditemukan")
+		return
+	}
+	if len(sheets) == 0 {
+		release()
+		writeError(w, http.StatusBadRequest, "Session masih kosong")
+		return
+	}
+	pairs, err := sessionPairs(sheets)
+	release()
+	if err != nil {
+		slog.Error("Gagal baca file session", "session", req.SessionID, "err", err)
+		writeError(w, http.StatusInternalServerError, "Gagal membaca hasil scan session")
+		return
+	}
+
+	save := dbclient.SaveRequest{
+		Mode:     req.Mode,
+		DocName:  req.DocName,
+		NPSN:     req.NPSN,
+		SNBapp:   req.SNBapp,
+		HasilCek: req.HasilCek,
+		Kode:     req.Kode,
+		Pages:    savePages(pairs),
+
+		SubmissionID: newSubmissionID(),
+	}
+
+	err = dbAPI(dbClient).Save(r.Context(), save)
+	var rejected *dbclient.APIError
+	switch {
+	case err == nil:
+		slog.Info("Dokumen terkirim", "session", req.SessionID, "npsn", req.NPSN, "sn_bapp", req.SNBapp)
+		sessions.remove(req.SessionID)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"queued":  false,
+			"message": "Dokumen berhasil dikirim ke server",
+		})
+	case errors.As(err, &rejected) && rejected.Rejected():
+		// Session tetap disimpen biar operator bisa benerin data & kirim ulang
+		slog.Warn("Dokumen ditolak server", "session", req.SessionID, "status", rejected.StatusCode, "err", rejected.Message)
+		writeError(w, rejected.StatusCode, rejected.Message)
+	default:
+		slog.Warn("Server db gak bisa dihubungi, masuk outbox", "session", req.SessionID, "err", err)
+		item, qErr := outbox.enqueue(save, err)
+		if qErr != nil {
+			slog.Error("Gagal simpan ke outbox", "session", req.SessionID, "err", qErr)
+			writeError(w, http.StatusBadGateway, "Server tidak bisa dihubungi dan outbox gagal menyimpan: "+err.Error())
+			return
+		}
+		sessions.remove(req.SessionID)
+		outbox.kick()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":   true,
+			"queued":    true,
+			"outbox_id": item.ID,
+			"message":   "Server tidak bisa dihubungi, dokumen disimpan di outbox dan akan dikirim ulang otomatis",
+		})
+	}
+}
+
+// outboxHandler: GET /outbox buat liat antrian,
+// POST /outbox?id=... buat kirim ulang sekarang, DELETE /outbox?id=... buat buang item
+func outboxHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w, r)
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	// ID outbox selalu angka, sekalian nutup celah path traversal ke file lain
+	id := r.URL.Query().Get("id")
+	if _, err := strconv.ParseUint(id, 10, 64); r.Method != http.MethodGet && err != nil {
+		writeError(w, http.StatusBadRequest, "Parameter id tidak valid")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		items, err := outbox.list()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Gagal membaca outbox")
+			return
+		}
+		summaries := []OutboxSummary{}
+		for _, it := range items {
+			summaries = append(summaries, OutboxSummary{
+				ID:          it.ID,
+				Status:      it.Status,
+				NPSN:        it.Request.NPSN,
+				SNBapp:      it.Request.SNBapp,
+				Attempts:    it.Attempts,
+				LastError:   it.LastError,
+				NextAttempt: it.NextAttempt,
+				CreatedAt:   it.CreatedAt,
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    summaries,
+		})
+	case http.MethodPost:
+		if err := outbox.retryNow(id); err != nil {
+			writeError(w, http.StatusNotFound, "Item outbox tidak ditemukan")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Response{Success: true, Message: "Item dijadwalkan kirim ulang"})
+	case http.MethodDelete:
+		if err := outbox.remove(id); err != nil {
+			writeError(w, http.StatusNotFound, "Item outbox tidak ditemukan")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Response{Success: true, Message: "Item outbox dihapus"})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
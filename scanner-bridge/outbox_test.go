@@ -0,0 +1,26 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewSubmissionID(t *testing.T) {
+	old := config.StationID
+	t.Cleanup(func() { config.StationID = old })
+
+	config.StationID = "lab-2"
+	a, b := newSubmissionID(), newSubmissionID()
+	if a == b {
+		t.Fatalf("dua submission ID sama: %s", a)
+	}
+	if !strings.HasPrefix(a, "lab-2-") || len(a) != len("lab-2-")+32 {
+		t.Errorf("format submission ID salah: %s", a)
+	}
+
+	// Station ID panjang dipotong biar muat di kolom service db
+	config.StationID = strings.Repeat("s", 200)
+	if id := newSubmissionID(); len(id) > 128 {
+		t.Errorf("submission ID %d karakter, maksimal 128", len(id))
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Pairing: frontend minta token sekali, operator konfirmasi lewat tray atau
+// console (ngetik kode yang tampil di frontend), lalu token itu wajib dikirim
+// di header X-Bridge-Token setiap request scan/profile/session.
+
+const (
+	tokenHeader    = "X-Bridge-Token"
+	pairRequestTTL = 5 * time.Minute
+)
+
+// Pairing yang udah disetujui. Token cuma disimpen hash-nya.
+type Pairing struct {
+	TokenHash string    `json:"token_hash"`
+	Origin    string    `json:"origin"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type pairRequest struct {
+	ID        string
+	Code      string
+	Origin    string
+	CreatedAt time.Time
+	Token     string // Diisi waktu disetujui, dikasih ke frontend sekali
+}
+
+type pairingStore struct {
+	mu       sync.Mutex
+	path     string
+	pairings []Pairing
+	pending  map[string]*pairRequest
+	// Dikabarin tiap ada permintaan baru / status berubah (buat tray)
+	changed chan struct{}
+}
+
+var pairings = &pairingStore{
+	pending: make(map[string]*pairRequest),
+	changed: make(chan struct{}, 1),
+}
+
+func (p *pairingStore) load() error {
+	dir, err := appDir()
+	if err != nil {
+		return err
+	}
+	p.path = filepath.Join(dir, "pairings.json")
+
+	data, err := os.ReadFile(p.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &p.pairings)
+}
+
+// save harus dipanggil sambil megang p.mu
+func (p *pairingStore) save() error {
+	data, err := json.MarshalIndent(p.pairings, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p.path, data, 0600)
+}
+
+func (p *pairingStore) notify() {
+	select {
+	case p.changed <- struct{}{}:
+	default:
+	}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (p *pairingStore) request(origin string) *pairRequest {
+	n, _ := rand.Int(rand.Reader, big.NewInt(1000000))
+	req := &pairRequest{
+		ID:        randomHex(16),
+		Code:      fmt.Sprintf("%06d", n.Int64()),
+		Origin:    origin,
+		CreatedAt: time.Now(),
+	}
+
+	p.mu.Lock()
+	p.expire()
+	p.pending[req.ID] = req
+	p.mu.Unlock()
+
+	slog.Info("Permintaan pairing", "origin", displayOrigin(origin), "request", req.ID)
+	// Kode cuma ke console (prompt buat operator), gak ikut ke file log
+	fmt.Printf("Kode pairing %s: setujui lewat tray, atau ketik kodenya di console lalu Enter.\n", req.Code)
+	p.notify()
+	return req
+}
+
+// expire buang permintaan yang kelamaan gak disetujui (pegang p.mu)
+func (p *pairingStore) expire() {
+	for id, req := range p.pending {
+		if time.Since(req.CreatedAt) > pairRequestTTL {
+			delete(p.pending, id)
+		}
+	}
+}
+
+// latestPending: permintaan terbaru yang belum disetujui (buat tray)
+func (p *pairingStore) latestPending() *pairRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.expire()
+
+	var latest *pairRequest
+	for _, req := range p.pending {
+		if req.Token == "" && (latest == nil || req.CreatedAt.After(latest.CreatedAt)) {
+			latest = req
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+	c := *latest
+	return &c
+}
+
+// approve nyetujui permintaan berdasarkan ID atau kode
+func (p *pairingStore) approve(match func(*pairRequest) bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.expire()
+
+	for _, req := range p.pending {
+		if req.Token != "" || !match(req) {
+			continue
+		}
+		req.Token = randomHex(32)
+		p.pairings = append(p.pairings, Pairing{
+			TokenHash: hashToken(req.Token),
+			Origin:    req.Origin,
+			CreatedAt: time.Now(),
+		})
+		if err := p.save(); err != nil {
+			slog.Error("Gagal simpan pairing", "err", err)
+		}
+		slog.Info("Pairing disetujui", "origin", displayOrigin(req.Origin), "request", req.ID)
+		p.notify()
+		return true
+	}
+	return false
+}
+
+// status dipolling frontend. Token cuma dikasih sekali, habis itu permintaannya dibuang.
+func (p *pairingStore) status(id string) (status, token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.expire()
+
+	req, ok := p.pending[id]
+	if !ok {
+		return "expired", ""
+	}
+	if req.Token == "" {
+		return "pending", ""
+	}
+	delete(p.pending, id)
+	p.notify()
+	return "approved", req.Token
+}
+
+func (p *pairingStore) revokeAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pairings = nil
+	if err := p.save(); err != nil {
+		slog.Error("Gagal simpan pairing", "err", err)
+	}
+	slog.Info("Semua pairing dicabut")
+}
+
+// valid ngecek token dari request. Token terikat ke origin waktu pairing.
+func (p *pairingStore) valid(token, origin string) bool {
+	if token == "" {
+		return false
+	}
+	h := hashToken(token)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, pr := range p.pairings {
+		if subtle.ConstantTimeCompare([]byte(pr.TokenHash), []byte(h)) == 1 {
+			return origin == "" || strings.EqualFold(pr.Origin, origin)
+		}
+	}
+	return false
+}
+
+// consoleApprover baca kode pairing yang diketik operator di console
+func (p *pairingStore) consoleApprover() {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		code := strings.TrimSpace(scanner.Text())
+		if code == "" {
+			continue
+		}
+		if !p.approve(func(req *pairRequest) bool { return req.Code == code }) {
+			fmt.Printf("Kode pairing %s tidak ditemukan / sudah kadaluarsa\n", code)
+		}
+	}
+}
+
+func displayOrigin(origin string) string {
+	if origin == "" {
+		return "(tanpa origin)"
+	}
+	return origin
+}
+
+// requirePairing: cek Origin ada di allowlist dan token pairing valid.
+// Preflight (OPTIONS) cukup lolos cek origin aja.
+func requirePairing(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin != "" && !originAllowed(origin) {
+			writeError(w, http.StatusForbidden, "Origin tidak diizinkan")
+			return
+		}
+		if r.Method != "OPTIONS" && !pairings.valid(r.Header.Get(tokenHeader), origin) {
+			enableCors(&w, r)
+			writeError(w, http.StatusUnauthorized, "Bridge belum dipasangkan (pairing) atau token tidak valid")
+			return
+		}
+		h(w, r)
+	}
+}
+
+// pairHandler: POST /pair buat minta pairing (balikin request_id & kode),
+// GET /pair?id=... buat polling status sampai disetujui operator
+func pairHandler(w http.ResponseWriter, r *http.Request) {
+	enableCors(&w, r)
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin != "" && !originAllowed(origin) {
+		writeError(w, http.StatusForbidden, "Origin tidak diizinkan")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		req := pairings.request(origin)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":    true,
+			"request_id": req.ID,
+			"code":       req.Code,
+			"message":    "Konfirmasi kode ini di tray atau console Scanner Bridge",
+		})
+	case http.MethodGet:
+		status, token := pairings.status(r.URL.Query().Get("id"))
+		resp := map[string]interface{}{
+			"success": status != "expired",
+			"status":  status,
+		}
+		if token != "" {
+			resp["token"] = token
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
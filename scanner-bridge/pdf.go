@@ -0,0 +1,433 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Parser PDF minimalis, cuma buat ngambil gambar halaman dari PDF hasil
+// scan (satu gambar per halaman). Urutan halaman diambil dari page tree,
+// bukan urutan objek di file, karena banyak generator nyimpen gambar di akhir.
+
+var (
+	pdfObjHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	pdfRef       = regexp.MustCompile(`(\d+)\s+\d+\s+R\b`)
+	pdfNamedRef  = regexp.MustCompile(`/([^\s/<>\[\]()]+)\s+(\d+)\s+\d+\s+R\b`)
+	pdfDoOp      = regexp.MustCompile(`/([^\s/<>\[\]()]+)\s+Do\b`)
+
+	// Key yang dibaca lewat pdfInt / pdfRefNum, regexp-nya dikompilasi sekali
+	pdfIntKeys = pdfKeyPatterns(`\s+(\d+)\b(\s+\d+\s+R)?`, "/N", "/First", "/Length", "/Width", "/Height", "/BitsPerComponent")
+	pdfRefKeys = pdfKeyPatterns(`\s+(\d+)\s+\d+\s+R\b`, "/Length", "/Resources", "/XObject", "/Pages", "/Contents")
+)
+
+func pdfKeyPatterns(suffix string, keys ...string) map[string]*regexp.Regexp {
+	m := make(map[string]*regexp.Regexp, len(keys))
+	for _, key := range keys {
+		m[key] = regexp.MustCompile(regexp.QuoteMeta(key) + suffix)
+	}
+	return m
+}
+
+// Batas hasil inflate stream selain gambar (object stream, content stream).
+// Gambar dibatasi maxImageSide / maxImagePixels, dicek sebelum di-inflate.
+const pdfMaxStreamBytes = 64 << 20
+
+type pdfDoc struct {
+	objs map[int][]byte // nomor objek -> isi antara "obj" dan "endobj"
+}
+
+func parsePDF(data []byte) (*pdfDoc, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\r\n\t "), []byte("%PDF")) {
+		return nil, fmt.Errorf("bukan file PDF")
+	}
+
+	doc := &pdfDoc{objs: make(map[int][]byte)}
+	for _, m := range pdfObjHeader.FindAllSubmatchIndex(data, -1) {
+		num, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+		start := m[1]
+		end := bytes.Index(data[start:], []byte("endobj"))
+		if end < 0 {
+			continue
+		}
+		// Kalau objek didefinisikan ulang (incremental update), yang terakhir menang
+		doc.objs[num] = data[start : start+end]
+	}
+
+	// Objek yang dikompres di dalam object stream (PDF 1.5+)
+	for _, body := range doc.objs {
+		if !bytes.Contains(pdfDict(body), []byte("/ObjStm")) {
+			continue
+		}
+		doc.loadObjStm(body)
+	}
+
+	if len(doc.objs) == 0 {
+		return nil, fmt.Errorf("PDF tidak berisi objek yang bisa dibaca")
+	}
+	return doc, nil
+}
+
+func (d *pdfDoc) loadObjStm(body []byte) {
+	dict := pdfDict(body)
+	n := pdfInt(dict, "/N")
+	first := pdfInt(dict, "/First")
+	raw := d.streamData(body)
+	if n <= 0 || first <= 0 || raw == nil || !bytes.Contains(dict, []byte("/FlateDecode")) {
+		return
+	}
+	content, err := inflate(raw, pdfMaxStreamBytes)
+	if err != nil || first > len(content) {
+		return
+	}
+
+	header := bytes.Fields(content[:first])
+	type entry struct{ num, off int }
+	var entries []entry
+	for i := 0; i+1 < len(header) && len(entries) < n; i += 2 {
+		num, err1 := strconv.Atoi(string(header[i]))
+		off, err2 := strconv.Atoi(string(header[i+1]))
+		if err1 != nil || err2 != nil || off < 0 || first+off < first {
+			return
+		}
+		entries = append(entries, entry{num, first + off})
+	}
+	for i, e := range entries {
+		end := len(content)
+		if i+1 < len(entries) {
+			end = entries[i+1].off
+		}
+		if e.off < first || e.off > end || end > len(content) {
+			continue
+		}
+		if _, exists := d.objs[e.num]; !exists {
+			d.objs[e.num] = content[e.off:end]
+		}
+	}
+}
+
+// pdfDict: bagian dictionary dari isi objek (sebelum keyword stream)
+func pdfDict(body []byte) []byte {
+	if i := bytes.Index(body, []byte("stream")); i >= 0 {
+		return body[:i]
+	}
+	return body
+}
+
+// pdfInt baca nilai integer langsung dari key (harus ada di pdfIntKeys),
+// -1 kalau gak ada
+func pdfInt(dict []byte, key string) int {
+	m := pdfIntKeys[key].FindSubmatch(dict)
+	if m == nil || len(m[2]) > 0 {
+		return -1
+	}
+	n, _ := strconv.Atoi(string(m[1]))
+	return n
+}
+
+// pdfRefNum nomor objek dari "key N 0 R" (key harus ada di pdfRefKeys),
+// -1 kalau bukan referensi
+func pdfRefNum(dict []byte, key string) int {
+	m := pdfRefKeys[key].FindSubmatch(dict)
+	if m == nil {
+		return -1
+	}
+	n, _ := strconv.Atoi(string(m[1]))
+	return n
+}
+
+// pdfSubDict ngambil isi <<...>> setelah key (nested dict ditangani)
+func pdfSubDict(dict []byte, key string) []byte {
+	i := bytes.Index(dict, []byte(key))
+	if i < 0 {
+		return nil
+	}
+	rest := bytes.TrimLeft(dict[i+len(key):], " \r\n\t")
+	if !bytes.HasPrefix(rest, []byte("<<")) {
+		return nil
+	}
+	depth := 0
+	for j := 0; j+1 < len(rest); j++ {
+		switch {
+		case rest[j] == '<' && rest[j+1] == '<':
+			depth++
+			j++
+		case rest[j] == '>' && rest[j+1] == '>':
+			depth--
+			j++
+			if depth == 0 {
+				return rest[2 : j-1]
+			}
+		}
+	}
+	return nil
+}
+
+// pdfValue resolve nilai key yang berupa dictionary, inline atau indirect
+func (d *pdfDoc) pdfValue(dict []byte, key string) []byte {
+	if sub := pdfSubDict(dict, key); sub != nil {
+		return sub
+	}
+	if num := pdfRefNum(dict, key); num >= 0 {
+		return pdfDict(d.objs[num])
+	}
+	return nil
+}
+
+// streamData ngambil byte mentah stream dari isi objek
+func (d *pdfDoc) streamData(body []byte) []byte {
+	i := bytes.Index(body, []byte("stream"))
+	if i < 0 {
+		return nil
+	}
+	start := i + len("stream")
+	if bytes.HasPrefix(body[start:], []byte("\r\n")) {
+		start += 2
+	} else if start < len(body) && body[start] == '\n' {
+		start++
+	}
+
+	dict := body[:i]
+	length := pdfInt(dict, "/Length")
+	if length < 0 {
+		// /Length indirect reference
+		if num := pdfRefNum(dict, "/Length"); num >= 0 {
+			if n, err := strconv.Atoi(string(bytes.TrimSpace(d.objs[num]))); err == nil {
+				length = n
+			}
+		}
+	}
+	if length >= 0 && start+length <= len(body) {
+		return body[start : start+length]
+	}
+
+	end := bytes.LastIndex(body, []byte("endstream"))
+	if end < start {
+		return nil
+	}
+	return bytes.TrimRight(body[start:end], "\r\n")
+}
+
+// pageRefs jalan di page tree dan balikin objek halaman sesuai urutan,
+// sekalian Resources yang diwarisin dari parent
+func (d *pdfDoc) pageRefs(num int, inherited []byte, seen map[int]bool, out *[][2][]byte) {
+	if seen[num] {
+		return
+	}
+	seen[num] = true
+
+	dict := pdfDict(d.objs[num])
+	res := d.pdfValue(dict, "/Resources")
+	if res == nil {
+		res = inherited
+	}
+
+	if kids := bytes.Index(dict, []byte("/Kids")); kids >= 0 {
+		rest := dict[kids:]
+		lb, rb := bytes.IndexByte(rest, '['), bytes.IndexByte(rest, ']')
+		if lb < 0 || rb < lb {
+			return
+		}
+		for _, m := range pdfRef.FindAllSubmatch(rest[lb:rb], -1) {
+			kid, _ := strconv.Atoi(string(m[1]))
+			d.pageRefs(kid, res, seen, out)
+		}
+		return
+	}
+	*out = append(*out, [2][]byte{dict, res})
+}
+
+func (d *pdfDoc) rootPages() int {
+	nums := make([]int, 0, len(d.objs))
+	for n := range d.objs {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+	for _, n := range nums {
+		dict := pdfDict(d.objs[n])
+		if !bytes.Contains(dict, []byte("/Catalog")) {
+			continue
+		}
+		if root := pdfRefNum(dict, "/Pages"); root >= 0 {
+			return root
+		}
+	}
+	return -1
+}
+
+// inflate zlib, hasilnya dipotong di limit byte biar PDF kecil gak bisa
+// mengembang jadi bergiga-giga di memori
+func inflate(raw []byte, limit int64) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(io.LimitReader(zr, limit))
+}
+
+// decodedStream: isi stream yang udah di-inflate kalau pakai FlateDecode,
+// maksimal limit byte
+func (d *pdfDoc) decodedStream(body []byte, limit int64) []byte {
+	raw := d.streamData(body)
+	if raw == nil || !bytes.Contains(pdfDict(body), []byte("/FlateDecode")) {
+		return raw
+	}
+	out, _ := inflate(raw, limit)
+	return out
+}
+
+// pageImageNames: nama XObject yang digambar di halaman (operator Do), sesuai urutan
+func (d *pdfDoc) pageImageNames(page []byte) []string {
+	var refs [][][]byte
+	if m := pdfRefKeys["/Contents"].FindSubmatch(page); m != nil {
+		refs = append(refs, m)
+	} else if i := bytes.Index(page, []byte("/Contents")); i >= 0 {
+		rest := page[i:]
+		lb, rb := bytes.IndexByte(rest, '['), bytes.IndexByte(rest, ']')
+		if lb >= 0 && rb > lb {
+			refs = pdfRef.FindAllSubmatch(rest[lb:rb], -1)
+		}
+	}
+
+	var names []string
+	for _, m := range refs {
+		num, _ := strconv.Atoi(string(m[1]))
+		for _, op := range pdfDoOp.FindAllSubmatch(d.decodedStream(d.objs[num], pdfMaxStreamBytes), -1) {
+			names = append(names, string(op[1]))
+		}
+	}
+	return names
+}
+
+// imageJPEG ngubah image XObject jadi JPEG. JPEG (/DCTDecode) diambil apa
+// adanya, raw 8-bit Gray/RGB (/FlateDecode) di-encode ulang. Format lain
+// (CCITT, JBIG2, filter berantai) balikin nil.
+func (d *pdfDoc) imageJPEG(body []byte) ([]byte, error) {
+	dict := pdfDict(body)
+	dct := bytes.Contains(dict, []byte("/DCTDecode"))
+	flate := bytes.Contains(dict, []byte("/FlateDecode"))
+
+	switch {
+	case dct && !flate:
+		img := d.streamData(body)
+		if len(img) < 4 || img[0] != 0xFF || img[1] != 0xD8 {
+			return nil, nil
+		}
+		if err := checkImageConfig(img); err != nil {
+			return nil, err
+		}
+		return img, nil
+	case flate && !dct:
+		w, h := pdfInt(dict, "/Width"), pdfInt(dict, "/Height")
+		if w <= 0 || h <= 0 || pdfInt(dict, "/BitsPerComponent") != 8 {
+			return nil, nil
+		}
+		// Predictor PNG (DecodeParms) gak didukung
+		if bytes.Contains(dict, []byte("/Predictor")) {
+			return nil, nil
+		}
+		// Width/Height dari file, dicek dalam int64 dulu biar w*h gak overflow
+		if err := checkImageSize(w, h); err != nil {
+			return nil, err
+		}
+		gray := bytes.Contains(dict, []byte("/DeviceGray"))
+		rgb := bytes.Contains(dict, []byte("/DeviceRGB"))
+		need := w * h
+		if rgb {
+			need *= 3
+		}
+		raw := d.decodedStream(body, int64(need))
+
+		var img image.Image
+		switch {
+		case gray && len(raw) >= need:
+			img = &image.Gray{Pix: raw[:w*h], Stride: w, Rect: image.Rect(0, 0, w, h)}
+		case rgb && len(raw) >= need:
+			rgba := image.NewRGBA(image.Rect(0, 0, w, h))
+			for i := 0; i < w*h; i++ {
+				copy(rgba.Pix[i*4:i*4+3], raw[i*3:i*3+3])
+				rgba.Pix[i*4+3] = 255
+			}
+			img = rgba
+		default:
+			return nil, nil
+		}
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+			return nil, fmt.Errorf("gagal encode jpeg: %v", err)
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, nil
+}
+
+// extractPDFImages ngambil gambar tiap halaman sesuai urutan halaman
+func extractPDFImages(data []byte) ([][]byte, error) {
+	doc, err := parsePDF(data)
+	if err != nil {
+		return nil, err
+	}
+	root := doc.rootPages()
+	if root < 0 {
+		return nil, fmt.Errorf("page tree PDF tidak ditemukan")
+	}
+
+	var pages [][2][]byte
+	doc.pageRefs(root, nil, make(map[int]bool), &pages)
+
+	var images [][]byte
+	for i, page := range pages {
+		// Nama XObject -> nomor objek
+		xobjects := make(map[string]int)
+		var order []string
+		for _, m := range pdfNamedRef.FindAllSubmatch(doc.pdfValue(page[1], "/XObject"), -1) {
+			num, _ := strconv.Atoi(string(m[2]))
+			xobjects[string(m[1])] = num
+			order = append(order, string(m[1]))
+		}
+		// Resources bisa dipakai bareng banyak halaman, jadi yang dipakai
+		// diambil dari content stream. Kalau gak kebaca, pakai semua.
+		names := doc.pageImageNames(page[0])
+		if len(names) == 0 {
+			names = order
+		}
+
+		found := false
+		for _, name := range names {
+			num, ok := xobjects[name]
+			if !ok {
+				continue
+			}
+			body := doc.objs[num]
+			dict := pdfDict(body)
+			if !bytes.Contains(dict, []byte("/Image")) {
+				continue
+			}
+			img, err := doc.imageJPEG(body)
+			if err != nil {
+				return nil, fmt.Errorf("halaman %d: %v", i+1, err)
+			}
+			if img == nil {
+				continue
+			}
+			images = append(images, img)
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("halaman %d tidak berisi gambar yang bisa diambil", i+1)
+		}
+	}
+
+	if len(images) == 0 {
+		return nil, fmt.Errorf("PDF tidak berisi halaman")
+	}
+	return images, nil
+}
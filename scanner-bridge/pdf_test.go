@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image/jpeg"
+	"strings"
+	"testing"
+)
+
+func deflate(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// objStmPDF bikin PDF kecil dengan satu object stream, header & isi bebas
+func objStmPDF(t *testing.T, header, objs string) []byte {
+	t.Helper()
+	content := []byte(header + objs)
+	stream := deflate(t, content)
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.5\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	fmt.Fprintf(&b, "9 0 obj\n<< /Type /ObjStm /N 2 /First %d /Filter /FlateDecode /Length %d >>\nstream\n", len(header), len(stream))
+	b.Write(stream)
+	b.WriteString("\nendstream\nendobj\n%%EOF\n")
+	return b.Bytes()
+}
+
+func TestLoadObjStm(t *testing.T) {
+	header := "2 0 3 29 "
+	objs := "<< /Type /Pages /Kids [] >>  << /Foo 1 >>"
+	doc, err := parsePDF(objStmPDF(t, header, objs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(doc.objs[2]); got != "<< /Type /Pages /Kids [] >>  " {
+		t.Errorf("objek 2 = %q", got)
+	}
+	if got := string(doc.objs[3]); got != "<< /Foo 1 >>" {
+		t.Errorf("objek 3 = %q", got)
+	}
+}
+
+func TestLoadObjStmBadOffsets(t *testing.T) {
+	objs := "<< /Type /Pages /Kids [] >>  << /Foo 1 >>"
+	for _, header := range []string{
+		"2 -44 3 0 ",                 // offset negatif
+		"2 0 3 -1 ",                  // offset negatif di entry terakhir
+		"2 9223372036854775800 3 0 ", // first+off overflow
+		"2 29 3 0 ",                  // offset mundur
+		"2 0 3 99999 ",               // lewat dari isi stream
+	} {
+		doc, err := parsePDF(objStmPDF(t, header, objs))
+		if err != nil {
+			t.Fatalf("%q: %v", header, err)
+		}
+		for num, body := range doc.objs {
+			if num != 1 && num != 9 && len(body) > len(objs) {
+				t.Errorf("%q: objek %d kepanjangan (%d byte)", header, num, len(body))
+			}
+		}
+	}
+}
+
+// imagePDF bikin PDF satu halaman dengan satu image XObject (FlateDecode)
+func imagePDF(t *testing.T, w, h int, colorSpace string, pixels []byte) []byte {
+	t.Helper()
+	stream := deflate(t, pixels)
+	content := "q 100 0 0 100 0 0 cm /Im0 Do Q"
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	b.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	b.WriteString("2 0 obj\n<< /Type /Pages /Kids [3 0 R] /Count 1 >>\nendobj\n")
+	b.WriteString("3 0 obj\n<< /Type /Page /Parent 2 0 R /Resources << /XObject << /Im0 5 0 R >> >> /Contents 4 0 R >>\nendobj\n")
+	fmt.Fprintf(&b, "4 0 obj\n<< /Length %d >>\nstream\n%s\nendstream\nendobj\n", len(content), content)
+	fmt.Fprintf(&b, "5 0 obj\n<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Filter /FlateDecode /Length %d >>\nstream\n", w, h, colorSpace, len(stream))
+	b.Write(stream)
+	b.WriteString("\nendstream\nendobj\n%%EOF\n")
+	return b.Bytes()
+}
+
+func TestExtractPDFImagesFlate(t *testing.T) {
+	images, err := extractPDFImages(imagePDF(t, 2, 2, "DeviceRGB", bytes.Repeat([]byte{200, 10, 10}, 4)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 1 {
+		t.Fatalf("dapet %d gambar, harusnya 1", len(images))
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(images[0]))
+	if err != nil || cfg.Width != 2 || cfg.Height != 2 {
+		t.Fatalf("jpeg %+v, err %v", cfg, err)
+	}
+}
+
+func TestExtractPDFImagesTooLarge(t *testing.T) {
+	for _, size := range [][2]int{
+		{3037000500, 3037000500}, // w*h overflow
+		{maxImageSide + 1, 1},
+		{maxImageSide, maxImageSide}, // lolos per sisi, lewat batas piksel
+	} {
+		_, err := extractPDFImages(imagePDF(t, size[0], size[1], "DeviceGray", make([]byte, 16)))
+		if err == nil || !strings.Contains(err.Error(), "terlalu besar") {
+			t.Errorf("%dx%d: err = %v", size[0], size[1], err)
+		}
+	}
+	// Data kurang dari Width*Height dilewati, bukan panic
+	if _, err := extractPDFImages(imagePDF(t, 100, 100, "DeviceGray", make([]byte, 16))); err == nil {
+		t.Error("gambar dengan data kurang harusnya gagal")
+	}
+}
+
+func TestInflateLimit(t *testing.T) {
+	out, err := inflate(deflate(t, make([]byte, 1<<20)), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1000 {
+		t.Fatalf("hasil inflate %d byte, harusnya dipotong di 1000", len(out))
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+)
+
+// Cek kualitas hasil scan (gelap, buram, miring, kepotong) biar operator
+// bisa langsung scan ulang sebelum dokumen diarsip.
+
+// Batas-batas warning, bisa diubah di config.json bagian "quality"
+type QualityThresholds struct {
+	MinBrightness float64 `json:"min_brightness"` // Rata-rata luma 0-255, di bawah ini = terlalu gelap
+	MinContrast   float64 `json:"min_contrast"`   // Standar deviasi luma, di bawah ini = pudar
+	MinSharpness  float64 `json:"min_sharpness"`  // Variance of Laplacian, di bawah ini = buram
+	MaxSkew       float64 `json:"max_skew"`       // Derajat, lebih dari ini = miring
+	MaxEdgeInk    float64 `json:"max_edge_ink"`   // Rasio piksel gelap di pinggir, lebih dari ini = kepotong
+}
+
+var defaultQualityThresholds = QualityThresholds{
+	MinBrightness: 90,
+	MinContrast:   20,
+	MinSharpness:  40,
+	MaxSkew:       1.5,
+	MaxEdgeInk:    0.08,
+}
+
+type QualityWarning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type PageQuality struct {
+	Brightness float64            `json:"brightness"`
+	Contrast   float64            `json:"contrast"`
+	Sharpness  float64            `json:"sharpness"`
+	SkewAngle  float64            `json:"skew_angle"`
+	EdgeInk    map[string]float64 `json:"edge_ink"`
+	Blank      bool               `json:"blank"`
+	Warnings   []QualityWarning   `json:"warnings,omitempty"`
+}
+
+// Sisi terpanjang gambar waktu dianalisis, biar cepet di PC station yang lemot
+const qualityMaxDim = 1000
+
+// grayImage: luma hasil downscale, row-major
+type grayImage struct {
+	w, h int
+	pix  []float64
+}
+
+func (g *grayImage) at(x, y int) float64 { return g.pix[y*g.w+x] }
+
+func toGray(img image.Image) *grayImage {
+	b := img.Bounds()
+	step := 1
+	if m := max(b.Dx(), b.Dy()); m > qualityMaxDim {
+		step = (m + qualityMaxDim - 1) / qualityMaxDim
+	}
+
+	g := &grayImage{w: b.Dx() / step, h: b.Dy() / step}
+	g.pix = make([]float64, g.w*g.h)
+	for y := 0; y < g.h; y++ {
+		for x := 0; x < g.w; x++ {
+			r, gg, bb, _ := img.At(b.Min.X+x*step, b.Min.Y+y*step).RGBA()
+			g.pix[y*g.w+x] = (0.299*float64(r) + 0.587*float64(gg) + 0.114*float64(bb)) / 257
+		}
+	}
+	return g
+}
+
+// analyzeQuality decode JPEG hasil proses dan ngitung metrik + warning
+func analyzeQuality(data []byte, t QualityThresholds) (*PageQuality, error) {
+	img, err := decodeJPEG(data)
+	if err != nil {
+		return nil, err
+	}
+	g := toGray(img)
+	if g.w < 3 || g.h < 3 {
+		return nil, fmt.Errorf("gambar terlalu kecil (%dx%d)", g.w, g.h)
+	}
+
+	q := &PageQuality{}
+
+	// Brightness & contrast
+	var sum, sumSq float64
+	for _, v := range g.pix {
+		sum += v
+		sumSq += v * v
+	}
+	n := float64(len(g.pix))
+	q.Brightness = sum / n
+	q.Contrast = math.Sqrt(math.Max(sumSq/n-q.Brightness*q.Brightness, 0))
+
+	// Sharpness: variance of Laplacian (makin kecil makin buram)
+	var lapSum, lapSq float64
+	for y := 1; y < g.h-1; y++ {
+		for x := 1; x < g.w-1; x++ {
+			l := g.at(x-1, y) + g.at(x+1, y) + g.at(x, y-1) + g.at(x, y+1) - 4*g.at(x, y)
+			lapSum += l
+			lapSq += l * l
+		}
+	}
+	ln := float64((g.w - 2) * (g.h - 2))
+	q.Sharpness = lapSq/ln - (lapSum/ln)*(lapSum/ln)
+
+	// Piksel "tinta": jauh lebih gelap dari rata-rata halaman
+	inkLevel := math.Min(q.Brightness-2*q.Contrast, 128)
+	if inkLevel < 40 {
+		inkLevel = 40
+	}
+	q.Blank = q.Contrast < t.MinContrast && q.Brightness >= t.MinBrightness
+
+	q.EdgeInk = edgeInk(g, inkLevel)
+	if !q.Blank {
+		q.SkewAngle = estimateSkew(g, inkLevel)
+	}
+
+	warn := func(code, format string, args ...interface{}) {
+		q.Warnings = append(q.Warnings, QualityWarning{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+	if q.Brightness < t.MinBrightness {
+		warn("dark", "Halaman terlalu gelap (brightness %.0f < %.0f)", q.Brightness, t.MinBrightness)
+	}
+	if !q.Blank {
+		if q.Contrast < t.MinContrast {
+			warn("low_contrast", "Kontras terlalu rendah (%.1f < %.1f)", q.Contrast, t.MinContrast)
+		}
+		if q.Sharpness < t.MinSharpness {
+			warn("blurry", "Halaman kemungkinan buram (sharpness %.1f < %.1f)", q.Sharpness, t.MinSharpness)
+		}
+		if math.Abs(q.SkewAngle) > t.MaxSkew {
+			warn("skewed", "Halaman miring %.1f derajat", q.SkewAngle)
+		}
+		for _, side := range []string{"top", "bottom", "left", "right"} {
+			if q.EdgeInk[side] > t.MaxEdgeInk {
+				warn("edge_"+side, "Isi dokumen menyentuh pinggir %s, mungkin kepotong", edgeNames[side])
+			}
+		}
+	}
+	return q, nil
+}
+
+var edgeNames = map[string]string{"top": "atas", "bottom": "bawah", "left": "kiri", "right": "kanan"}
+
+// edgeInk: rasio piksel gelap di pita 1.5% tiap sisi
+func edgeInk(g *grayImage, inkLevel float64) map[string]float64 {
+	bandX := max(1, g.w*15/1000)
+	bandY := max(1, g.h*15/1000)
+
+	ratio := func(x0, y0, x1, y1 int) float64 {
+		dark, total := 0, 0
+		for y := y0; y < y1; y++ {
+			for x := x0; x < x1; x++ {
+				if g.at(x, y) < inkLevel {
+					dark++
+				}
+				total++
+			}
+		}
+		if total == 0 {
+			return 0
+		}
+		return float64(dark) / float64(total)
+	}
+
+	return map[string]float64{
+		"top":    ratio(0, 0, g.w, bandY),
+		"bottom": ratio(0, g.h-bandY, g.w, g.h),
+		"left":   ratio(0, 0, bandX, g.h),
+		"right":  ratio(g.w-bandX, 0, g.w, g.h),
+	}
+}
+
+// estimateSkew pakai projection profile: coba sudut -5..5 derajat, sudut yang
+// bikin baris-baris teks paling "rapat" (variance histogram tertinggi) menang
+func estimateSkew(g *grayImage, inkLevel float64) float64 {
+	type pt struct{ x, y float64 }
+	var ink []pt
+	for y := 0; y < g.h; y++ {
+		for x := 0; x < g.w; x++ {
+			if g.at(x, y) < inkLevel {
+				ink = append(ink, pt{float64(x), float64(y)})
+			}
+		}
+	}
+	if len(ink) < 100 {
+		return 0
+	}
+
+	bins := make([]float64, g.h*2+g.w)
+	offset := float64(g.w)
+	best, bestScore := 0.0, -1.0
+	for a := -5.0; a <= 5.0; a += 0.25 {
+		for i := range bins {
+			bins[i] = 0
+		}
+		tan := math.Tan(a * math.Pi / 180)
+		for _, p := range ink {
+			i := int(p.y - p.x*tan + offset)
+			if i >= 0 && i < len(bins) {
+				bins[i]++
+			}
+		}
+		var score float64
+		for _, c := range bins {
+			score += c * c
+		}
+		if score > bestScore {
+			best, bestScore = a, score
+		}
+	}
+	return best
+}
+
+// Sudut minimal yang dikoreksi deskew, di bawah ini gak keliatan miring
+const minDeskewAngle = 0.25
+
+// deskew muter balik halaman yang miring (pakai estimateSkew), sisa sudutnya
+// diisi putih. Balikin gambar asli kalau gak perlu dikoreksi.
+func deskew(img image.Image) (image.Image, float64) {
+	g := toGray(img)
+	if g.w < 3 || g.h < 3 {
+		return img, 0
+	}
+	var sum, sumSq float64
+	for _, v := range g.pix {
+		sum += v
+		sumSq += v * v
+	}
+	n := float64(len(g.pix))
+	mean := sum / n
+	std := math.Sqrt(math.Max(sumSq/n-mean*mean, 0))
+	inkLevel := math.Max(math.Min(mean-2*std, 128), 40)
+
+	angle := estimateSkew(g, inkLevel)
+	if math.Abs(angle) < minDeskewAngle {
+		return img, 0
+	}
+	return rotateImage(img, angle), angle
+}
+
+// rotateImage muter gambar sebesar angle derajat di sekitar titik tengah
+// (bilinear), ukuran tetap sama
+func rotateImage(img image.Image, angle float64) *image.RGBA {
+	b := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+
+	w, h := b.Dx(), b.Dy()
+	dst := image.NewRGBA(src.Bounds())
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+	cx, cy := float64(w-1)/2, float64(h-1)/2
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dx, dy := float64(x)-cx, float64(y)-cy
+			sx := cx + dx*cos - dy*sin
+			sy := cy + dx*sin + dy*cos
+
+			x0, y0 := int(math.Floor(sx)), int(math.Floor(sy))
+			di := y*dst.Stride + x*4
+			if x0 < 0 || y0 < 0 || x0+1 >= w || y0+1 >= h {
+				// Di luar gambar asli: putih
+				dst.Pix[di], dst.Pix[di+1], dst.Pix[di+2], dst.Pix[di+3] = 255, 255, 255, 255
+				continue
+			}
+			fx, fy := sx-float64(x0), sy-float64(y0)
+			i00 := y0*src.Stride + x0*4
+			i10 := i00 + 4
+			i01 := i00 + src.Stride
+			i11 := i01 + 4
+			for c := 0; c < 4; c++ {
+				top := float64(src.Pix[i00+c])*(1-fx) + float64(src.Pix[i10+c])*fx
+				bottom := float64(src.Pix[i01+c])*(1-fx) + float64(src.Pix[i11+c])*fx
+				dst.Pix[di+c] = uint8(top*(1-fy) + bottom*fy + 0.5)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+)
+
+// Response session bisa ratusan halaman 300 dpi. Dulu semua halaman di-base64
+// ke memory dulu baru json.Encode, RAM PC station lama langsung habis.
+// Sekarang halaman ditulis satu per satu langsung ke koneksi.
+
+type ResponseLimits struct {
+	MaxPages int `json:"max_pages"` // Total halaman (depan + belakang) per session
+	MaxMB    int `json:"max_mb"`    // Total ukuran JPEG per session (sebelum base64)
+}
+
+var defaultResponseLimits = ResponseLimits{
+	MaxPages: 300,
+	MaxMB:    150,
+}
+
+// Error kalau session bakal ngelewatin batas, dibalikin ke client sebagai 413
+type limitError struct {
+	Message string
+}
+
+func (e *limitError) Error() string { return e.Message }
+
+// checkLimits ngecek jumlah halaman & ukuran file sheet terhadap config.Limits
+func checkLimits(sheets []Sheet) error {
+	limits := config.Limits
+	var pages int
+	var size int64
+	for _, sh := range sheets {
+		for _, path := range []string{sh.Front, sh.Back} {
+			if path == "" {
+				continue
+			}
+			pages++
+			if info, err := os.Stat(path); err == nil {
+				size += info.Size()
+			}
+		}
+	}
+
+	if limits.MaxPages > 0 && pages > limits.MaxPages {
+		return &limitError{fmt.Sprintf("Session jadi %d halaman, batasnya %d. Pecah dokumen jadi beberapa session.", pages, limits.MaxPages)}
+	}
+	if limits.MaxMB > 0 && size > int64(limits.MaxMB)<<20 {
+		return &limitError{fmt.Sprintf("Session jadi %.1f MB, batasnya %d MB. Pecah dokumen atau turunin resolusi scan.", float64(size)/(1<<20), limits.MaxMB)}
+	}
+	return nil
+}
+
+// writeSession kirim isi session sebagai Response, streaming per halaman
+func writeSession(w http.ResponseWriter, sessionID string) {
+	sheets, release, ok := sessions.borrow(sessionID)
+	if !ok {
+		writeError(w, http.StatusNotFound, "Session tidak ditemukan")
+		return
+	}
+	defer release()
+	// Session lama bisa aja kegedean kalau batas di config diturunin
+	if err := checkLimits(sheets); err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+
+	id, _ := json.Marshal(sessionID)
+	w.Header().Set("Content-Type", "application/json")
+	cw := &countingWriter{w: w}
+	defer func() { responseBytes.observe(float64(cw.n)) }()
+	bw := bufio.NewWriterSize(cw, 64<<10)
+	fmt.Fprintf(bw, `{"success":true,"session_id":%s,"data":[`, id)
+	for i, sh := range sheets {
+		if i > 0 {
+			bw.WriteByte(',')
+		}
+		if err := writePair(bw, sh); err != nil {
+			// Header udah terkirim, putusin koneksi biar client tau response-nya gak lengkap
+			slog.Error("Gagal kirim halaman session", "session", sessionID, "err", err)
+			panic(http.ErrAbortHandler)
+		}
+	}
+	bw.WriteString("]}\n")
+	if err := bw.Flush(); err != nil {
+		slog.Warn("Gagal kirim response session", "session", sessionID, "err", err)
+	}
+}
+
+// writePair nulis satu ScanPair. Gambar di-stream lewat encoder base64,
+// field sisanya (kualitas, metadata) pakai encoding/json biasa.
+func writePair(w *bufio.Writer, sh Sheet) error {
+	w.WriteString(`{"front":`)
+	if err := writeDataURI(w, sh.Front); err != nil {
+		return err
+	}
+	if sh.Back != "" {
+		w.WriteString(`,"back":`)
+		if err := writeDataURI(w, sh.Back); err != nil {
+			return err
+		}
+	}
+
+	info, err := json.Marshal(sh.PageInfo)
+	if err != nil {
+		return err
+	}
+	if len(info) > 2 { // Bukan "{}"
+		w.WriteByte(',')
+		w.Write(info[1:]) // Buang "{" pembuka, "}" penutupnya dipakai
+		return nil
+	}
+	return w.WriteByte('}')
+}
+
+func writeDataURI(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	io.WriteString(w, `"data:image/jpeg;base64,`)
+	enc := base64.NewEncoder(base64.StdEncoding, w)
+	if _, err := io.Copy(enc, f); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, `"`)
+	return err
+}
+
+// countingWriter ngitung byte yang beneran ketulis (buat metrics ukuran response)
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
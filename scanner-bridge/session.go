@@ -0,0 +1,304 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Session nyimpen hasil scan per sheet di disk, jadi kalau kertas nyangkut
+// di tengah batch operator tinggal scan ulang sheet itu aja (append, insert,
+// atau replace) tanpa ngulang dari awal.
+
+// Berapa lama session boleh nganggur sebelum dihapus janitor
+const sessionTTL = 2 * time.Hour
+
+// Mode edit session waktu scan tambahan
+const (
+	ModeAppend  = "append"  // Tambah di akhir
+	ModeInsert  = "insert"  // Sisipin sebelum sheet ke-N
+	ModeReplace = "replace" // Ganti sheet ke-N dengan hasil scan baru
+)
+
+// Asal session
+const (
+	SourceScanner   = "scanner"
+	SourceHotFolder = "hotfolder"
+	SourceImport    = "import"
+)
+
+// Satu lembar kertas: depan & belakang (path JPEG yang udah diproses)
+type Sheet struct {
+	Front string
+	Back  string
+	PageInfo
+}
+
+type ScanSession struct {
+	ID        string
+	Profile   string
+	Source    string
+	Dir       string
+	Sheets    []Sheet
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// Stream / submit yang lagi baca file sheet (lihat borrow). Selama masih
+	// ada, file sheet yang diganti & folder session yang dihapus ditunda.
+	readers int
+	trash   []Sheet
+	removed bool
+}
+
+type sessionStore struct {
+	mu       sync.Mutex
+	root     string
+	sessions map[string]*ScanSession
+}
+
+var sessions = &sessionStore{
+	root:     filepath.Join(os.TempDir(), "owo_scan_sessions"),
+	sessions: make(map[string]*ScanSession),
+}
+
+func (s *sessionStore) create(profile, source string) (*ScanSession, error) {
+	now := time.Now()
+	id := fmt.Sprintf("%d", now.UnixNano())
+	dir := filepath.Join(s.root, id)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	sess := &ScanSession{
+		ID:        id,
+		Profile:   profile,
+		Source:    source,
+		Dir:       dir,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	s.mu.Lock()
+	s.sessions[id] = sess
+	s.mu.Unlock()
+	return sess, nil
+}
+
+func (s *sessionStore) get(id string) *ScanSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions[id]
+}
+
+// snapshot balikin salinan sheet biar bisa dibaca tanpa megang lock
+func (s *sessionStore) snapshot(id string) ([]Sheet, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		return nil, false
+	}
+	return append([]Sheet(nil), sess.Sheets...), true
+}
+
+// borrow kayak snapshot, tapi file sheet-nya dijamin gak dihapus (replace /
+// remove) sampai release dipanggil. Dipakai yang baca file pelan-pelan,
+// mis. stream response.
+func (s *sessionStore) borrow(id string) (sheets []Sheet, release func(), ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		return nil, nil, false
+	}
+	sess.readers++
+	return append([]Sheet(nil), sess.Sheets...), func() { s.release(sess) }, true
+}
+
+func (s *sessionStore) release(sess *ScanSession) {
+	s.mu.Lock()
+	sess.readers--
+	if sess.readers > 0 {
+		s.mu.Unlock()
+		return
+	}
+	trash, removed := sess.trash, sess.removed
+	sess.trash = nil
+	s.mu.Unlock()
+
+	if removed {
+		os.RemoveAll(sess.Dir)
+		return
+	}
+	for _, old := range trash {
+		removeSheetFiles(old)
+	}
+}
+
+// Ringkasan session buat endpoint /sessions
+type SessionSummary struct {
+	ID        string    `json:"id"`
+	Profile   string    `json:"profile"`
+	Source    string    `json:"source"`
+	Sheets    int       `json:"sheets"`
+	Warnings  int       `json:"warnings"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// list balikin ringkasan semua session (filter source opsional), terbaru duluan
+func (s *sessionStore) list(source string) []SessionSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	summaries := []SessionSummary{}
+	for _, sess := range s.sessions {
+		if source != "" && sess.Source != source {
+			continue
+		}
+		warnings := 0
+		for _, sh := range sess.Sheets {
+			for _, q := range []*PageQuality{sh.FrontQuality, sh.BackQuality} {
+				if q != nil {
+					warnings += len(q.Warnings)
+				}
+			}
+		}
+		summaries = append(summaries, SessionSummary{
+			ID:        sess.ID,
+			Profile:   sess.Profile,
+			Source:    sess.Source,
+			Sheets:    len(sess.Sheets),
+			Warnings:  warnings,
+			CreatedAt: sess.CreatedAt,
+			UpdatedAt: sess.UpdatedAt,
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].CreatedAt.After(summaries[j].CreatedAt) })
+	return summaries
+}
+
+// checkEdit validasi mode & nomor sheet (1-based) terhadap jumlah sheet sekarang
+func checkEdit(mode string, sheet, count int) error {
+	switch mode {
+	case ModeAppend:
+		return nil
+	case ModeInsert:
+		if sheet < 1 || sheet > count+1 {
+			return fmt.Errorf("sheet %d di luar jangkauan (1-%d)", sheet, count+1)
+		}
+	case ModeReplace:
+		if sheet < 1 || sheet > count {
+			return fmt.Errorf("sheet %d di luar jangkauan (1-%d)", sheet, count)
+		}
+	default:
+		return fmt.Errorf("mode %q tidak dikenal", mode)
+	}
+	return nil
+}
+
+// editSheets: hasil edit tanpa ngubah slice asli, plus sheet yang kebuang
+func editSheets(sheets []Sheet, mode string, sheet int, added []Sheet) (result, replaced []Sheet) {
+	result = append([]Sheet(nil), sheets...)
+	switch mode {
+	case ModeAppend:
+		result = append(result, added...)
+	case ModeInsert:
+		i := sheet - 1
+		result = append(result[:i], append(append([]Sheet(nil), added...), sheets[i:]...)...)
+	case ModeReplace:
+		i := sheet - 1
+		replaced = append(replaced, sheets[i])
+		result = append(result[:i], append(append([]Sheet(nil), added...), sheets[i+1:]...)...)
+	}
+	return result, replaced
+}
+
+// apply masukin sheet baru ke session sesuai mode. Buat replace, sheet lama
+// diganti semua sheet baru (kalau scan ulangnya lebih dari satu lembar).
+func (s *sessionStore) apply(id, mode string, sheet int, added []Sheet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[id]
+	if !ok {
+		return fmt.Errorf("session %s tidak ditemukan", id)
+	}
+	if err := checkEdit(mode, sheet, len(sess.Sheets)); err != nil {
+		return err
+	}
+
+	result, replaced := editSheets(sess.Sheets, mode, sheet, added)
+	// Cek batas di sini biar session gak pernah kegedean buat dikirim balik
+	if err := checkLimits(result); err != nil {
+		return err
+	}
+	sess.Sheets = result
+	sess.UpdatedAt = time.Now()
+
+	if sess.readers > 0 {
+		// Masih ada yang nge-stream sheet lama, hapusnya nanti di release
+		sess.trash = append(sess.trash, replaced...)
+		return nil
+	}
+	for _, old := range replaced {
+		removeSheetFiles(old)
+	}
+	return nil
+}
+
+func (s *sessionStore) remove(id string) bool {
+	s.mu.Lock()
+	sess, ok := s.sessions[id]
+	delete(s.sessions, id)
+	busy := ok && sess.readers > 0
+	if busy {
+		sess.removed = true
+	}
+	s.mu.Unlock()
+
+	if ok && !busy {
+		os.RemoveAll(sess.Dir)
+	}
+	return ok
+}
+
+// reset ngebuang sisa session dari run sebelumnya (crash/restart),
+// dipanggil sekali sebelum server jalan
+func (s *sessionStore) reset() {
+	os.RemoveAll(s.root)
+}
+
+// janitor ngehapus session yang udah lama gak disentuh
+func (s *sessionStore) janitor(ttl time.Duration) {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		var expired []string
+		s.mu.Lock()
+		for id, sess := range s.sessions {
+			if time.Since(sess.UpdatedAt) > ttl {
+				expired = append(expired, id)
+			}
+		}
+		s.mu.Unlock()
+
+		for _, id := range expired {
+			slog.Info("Session expired, dihapus", "session", id)
+			s.remove(id)
+		}
+	}
+}
+
+func removeSheetFiles(sh Sheet) {
+	if sh.Front != "" {
+		os.Remove(sh.Front)
+	}
+	if sh.Back != "" {
+		os.Remove(sh.Back)
+	}
+}
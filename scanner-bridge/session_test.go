@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testSheet bikin satu sheet dengan file depan beneran di folder session
+func testSheet(t *testing.T, sess *ScanSession, name string) Sheet {
+	t.Helper()
+	path := filepath.Join(sess.Dir, name+".jpg")
+	if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return Sheet{Front: path}
+}
+
+func testSessions(t *testing.T) *sessionStore {
+	t.Helper()
+	return &sessionStore{root: t.TempDir(), sessions: make(map[string]*ScanSession)}
+}
+
+func TestSessionReplaceWhileBorrowed(t *testing.T) {
+	s := testSessions(t)
+	sess, err := s.create("Test", SourceScanner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := testSheet(t, sess, "lama")
+	if err := s.apply(sess.ID, ModeAppend, 0, []Sheet{old}); err != nil {
+		t.Fatal(err)
+	}
+
+	sheets, release, ok := s.borrow(sess.ID)
+	if !ok || len(sheets) != 1 {
+		t.Fatalf("borrow: ok %v, %d sheet", ok, len(sheets))
+	}
+	if err := s.apply(sess.ID, ModeReplace, 1, []Sheet{testSheet(t, sess, "baru")}); err != nil {
+		t.Fatal(err)
+	}
+	// Stream masih baca sheet lama, file-nya harus tetap ada
+	if _, err := os.Stat(old.Front); err != nil {
+		t.Fatalf("file sheet lama kehapus waktu masih di-stream: %v", err)
+	}
+	release()
+	if _, err := os.Stat(old.Front); !os.IsNotExist(err) {
+		t.Errorf("file sheet lama gak dihapus setelah release: %v", err)
+	}
+
+	// Tanpa reader, replace langsung hapus file lama
+	prev, _ := s.snapshot(sess.ID)
+	if err := s.apply(sess.ID, ModeReplace, 1, []Sheet{testSheet(t, sess, "baru2")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(prev[0].Front); !os.IsNotExist(err) {
+		t.Errorf("file sheet yang diganti masih ada: %v", err)
+	}
+}
+
+func TestSessionRemoveWhileBorrowed(t *testing.T) {
+	s := testSessions(t)
+	sess, err := s.create("Test", SourceScanner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.apply(sess.ID, ModeAppend, 0, []Sheet{testSheet(t, sess, "satu")})
+
+	_, release, _ := s.borrow(sess.ID)
+	if !s.remove(sess.ID) {
+		t.Fatal("remove gagal")
+	}
+	if s.get(sess.ID) != nil {
+		t.Error("session masih kelihatan setelah remove")
+	}
+	if _, err := os.Stat(sess.Dir); err != nil {
+		t.Fatalf("folder session kehapus waktu masih di-stream: %v", err)
+	}
+	release()
+	if _, err := os.Stat(sess.Dir); !os.IsNotExist(err) {
+		t.Errorf("folder session gak dihapus setelah release: %v", err)
+	}
+}
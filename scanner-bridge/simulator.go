@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Backend simulator: scanner palsu buat development frontend & test otomatis.
+// Halaman digambar sendiri (label, nomor halaman, barcode opsional) sesuai
+// file skenario, jadi /scan bisa dicoba tanpa ADF duplex beneran.
+
+// Profile simulator di config.json
+type SimulatorProfile struct {
+	Name string `json:"name"` // Nama yang muncul di /profiles
+	// Path file skenario (JSON). Path relatif dihitung dari folder AppData bridge.
+	// Dibaca ulang tiap scan, jadi bisa diedit tanpa restart.
+	Scenario string `json:"scenario"`
+}
+
+const (
+	simErrPaperJam = "paper_jam"
+	simErrTimeout  = "timeout"
+	simErrNoPaper  = "no_paper"
+	simErrOffline  = "offline"
+)
+
+// SimulatorScenario: isi file skenario
+type SimulatorScenario struct {
+	Sheets     int    `json:"sheets"`      // Jumlah lembar (default 1)
+	Duplex     bool   `json:"duplex"`      // Tiap lembar dua halaman
+	BlankBacks bool   `json:"blank_backs"` // Halaman belakang kosong (cuma kalau duplex)
+	Label      string `json:"label"`       // Judul di tiap halaman
+	// Isi barcode Code 39, "{sheet}" diganti nomor lembar. Kosong = tanpa barcode.
+	Barcode     string  `json:"barcode"`
+	SkewDegrees float64 `json:"skew_degrees"` // Miringin halaman (buat ngetes warning / deskew)
+	Deskew      bool    `json:"deskew"`       // Nyalain deskew di pipeline
+	UpsideDown  bool    `json:"upside_down"`  // Halaman kebalik kayak NAPS2, pipeline yang muter balik
+	DPI         int     `json:"dpi"`          // Resolusi halaman A4 (default 100)
+	PageDelayMs int     `json:"page_delay_ms"`
+
+	// Error yang disuntik: paper_jam, timeout, no_paper, offline
+	Error           string `json:"error"`
+	ErrorAfterPages int    `json:"error_after_pages"` // paper_jam / timeout setelah sekian halaman
+	TimeoutSeconds  int    `json:"timeout_seconds"`   // Lama nunggu sebelum timeout (default 30)
+}
+
+func simulatorProfile(name string) (SimulatorProfile, bool) {
+	for _, p := range config.Simulator {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return SimulatorProfile{}, false
+}
+
+func loadScenario(path string) (SimulatorScenario, error) {
+	var sc SimulatorScenario
+	if !filepath.IsAbs(path) {
+		dir, err := appDir()
+		if err != nil {
+			return sc, err
+		}
+		path = filepath.Join(dir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return sc, fmt.Errorf("gagal baca skenario simulator: %v", err)
+	}
+	if err := json.Unmarshal(data, &sc); err != nil {
+		return sc, fmt.Errorf("skenario simulator rusak: %v", err)
+	}
+
+	if sc.Sheets <= 0 {
+		sc.Sheets = 1
+	}
+	if sc.DPI <= 0 {
+		sc.DPI = 100
+	}
+	if sc.TimeoutSeconds <= 0 {
+		sc.TimeoutSeconds = 30
+	}
+	if sc.Label == "" {
+		sc.Label = "SIMULATOR"
+	}
+	switch sc.Error {
+	case "", simErrPaperJam, simErrTimeout, simErrNoPaper, simErrOffline:
+	default:
+		return sc, fmt.Errorf("error simulator tidak dikenal: %s", sc.Error)
+	}
+	for _, c := range strings.ToUpper(strings.ReplaceAll(sc.Barcode, "{sheet}", "0")) {
+		if _, ok := code39[c]; !ok || c == '*' {
+			return sc, fmt.Errorf("karakter barcode tidak didukung Code 39: %q", c)
+		}
+	}
+	return sc, nil
+}
+
+type simulatorBackend struct {
+	profile  SimulatorProfile
+	scenario SimulatorScenario
+}
+
+func (b *simulatorBackend) Scan(ctx context.Context, lg *slog.Logger, dir string) ([]string, error) {
+	sc, err := loadScenario(b.profile.Scenario)
+	if err != nil {
+		return nil, err
+	}
+	b.scenario = sc
+	lg.Info("Scanning dengan simulator", "scenario", b.profile.Scenario, "sheets", sc.Sheets, "error", sc.Error)
+
+	switch sc.Error {
+	case simErrOffline:
+		return nil, fmt.Errorf("Gagal scan: scanner simulator offline")
+	case simErrNoPaper:
+		return nil, fmt.Errorf("Gagal scan: tidak ada kertas di feeder (simulator)")
+	}
+
+	pagesPerSheet := 1
+	if sc.Duplex {
+		pagesPerSheet = 2
+	}
+	total := sc.Sheets * pagesPerSheet
+
+	var files []string
+	for i := 0; i < total; i++ {
+		if sc.Error != "" && i == sc.ErrorAfterPages {
+			return nil, b.fail(ctx, sc, i)
+		}
+		if sc.PageDelayMs > 0 {
+			if err := sleepCtx(ctx, time.Duration(sc.PageDelayMs)*time.Millisecond); err != nil {
+				return nil, fmt.Errorf("Gagal scan: %v", err)
+			}
+		}
+
+		sheet, back := i/pagesPerSheet+1, i%pagesPerSheet == 1
+		var img image.Image
+		if back && sc.BlankBacks {
+			img = blankPage(sc.DPI)
+		} else {
+			img = simulatedPage(sc, sheet, i+1, back)
+		}
+		if sc.SkewDegrees != 0 {
+			img = rotateImage(img, sc.SkewDegrees)
+		}
+		if sc.UpsideDown {
+			img = rotateImage(img, 180)
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("sim_%04d.jpg", i+1))
+		if err := writeJPEG(path, img); err != nil {
+			return nil, fmt.Errorf("Simulator gagal nulis halaman: %v", err)
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// fail: error yang disuntik di tengah scan (halaman sebelumnya ikut hilang,
+// sama kayak NAPS2 yang gagal di tengah jalan)
+func (b *simulatorBackend) fail(ctx context.Context, sc SimulatorScenario, page int) error {
+	if sc.Error == simErrTimeout {
+		if err := sleepCtx(ctx, time.Duration(sc.TimeoutSeconds)*time.Second); err != nil {
+			return fmt.Errorf("Gagal scan: %v", err)
+		}
+		return fmt.Errorf("Gagal scan: scanner tidak merespon setelah %d detik (simulator, halaman %d)", sc.TimeoutSeconds, page+1)
+	}
+	return fmt.Errorf("Gagal scan: kertas macet (paper jam) di halaman %d (simulator)", page+1)
+}
+
+func (b *simulatorBackend) Name() string { return "simulator" }
+
+func (b *simulatorBackend) Pipeline() pipelineOptions {
+	return pipelineOptions{
+		Rotate: b.scenario.UpsideDown,
+		Duplex: b.scenario.Duplex,
+		Deskew: b.scenario.Deskew,
+		DPI:    b.scenario.DPI,
+	}
+}
+
+// A4 di resolusi dpi
+func pageSize(dpi int) (int, int) {
+	return dpi * 210 * 10 / 254, dpi * 297 * 10 / 254
+}
+
+func blankPage(dpi int) *image.RGBA {
+	w, h := pageSize(dpi)
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	xdraw.Draw(img, img.Bounds(), image.White, image.Point{}, xdraw.Src)
+	return img
+}
+
+// simulatedPage gambar halaman palsu: label, nomor halaman, barcode, dan
+// beberapa baris "teks" biar cek kualitas & deskew punya bahan
+func simulatedPage(sc SimulatorScenario, sheet, page int, back bool) *image.RGBA {
+	img := blankPage(sc.DPI)
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	unit := sc.DPI / 25 // Kira-kira 1 mm
+	if unit < 1 {
+		unit = 1
+	}
+	margin := unit * 15
+
+	side := "DEPAN"
+	if back {
+		side = "BELAKANG"
+	}
+	y := margin
+	y += drawText(img, sc.Label, margin, y, unit) + unit*4
+	y += drawText(img, fmt.Sprintf("LEMBAR %d %s", sheet, side), margin, y, unit) + unit*2
+	y += drawText(img, fmt.Sprintf("HALAMAN %d", page), margin, y, unit) + unit*8
+
+	if sc.Barcode != "" && !back {
+		value := strings.ToUpper(strings.ReplaceAll(sc.Barcode, "{sheet}", strconv.Itoa(sheet)))
+		y += drawCode39(img, value, margin, y, unit/2+1, unit*15) + unit*8
+	}
+
+	// Baris teks palsu: blok abu-abu gelap dengan panjang bervariasi
+	ink := color.RGBA{40, 40, 40, 255}
+	lineH, gap := unit*2, unit*4
+	for line := 0; y+lineH < h-margin; line++ {
+		end := w - margin - (line*37%5)*unit*12
+		for x := margin; x < end; {
+			word := unit * (4 + (x/unit+line*7)%9)
+			fillRect(img, x, y, min(x+word, end), y+lineH, ink)
+			x += word + unit*2
+		}
+		y += lineH + gap
+	}
+	return img
+}
+
+// drawText: basicfont 7x13 diperbesar biar kebaca, balikin tinggi yang dipakai
+func drawText(dst *image.RGBA, s string, x, y, unit int) int {
+	face := basicfont.Face7x13
+	small := image.NewRGBA(image.Rect(0, 0, face.Advance*len(s), face.Height))
+	xdraw.Draw(small, small.Bounds(), image.White, image.Point{}, xdraw.Src)
+	d := font.Drawer{Dst: small, Src: image.Black, Face: face, Dot: fixed.P(0, face.Ascent)}
+	d.DrawString(s)
+
+	scale := unit
+	if scale < 2 {
+		scale = 2
+	}
+	r := image.Rect(x, y, x+small.Bounds().Dx()*scale, y+face.Height*scale)
+	xdraw.NearestNeighbor.Scale(dst, r, small, small.Bounds(), xdraw.Src, nil)
+	return r.Dy()
+}
+
+// Code 39: tiap karakter 9 elemen (bar, spasi, bar, ...), w = lebar, n = sempit
+var code39 = map[rune]string{
+	'0': "nnnwwnwnn", '1': "wnnwnnnnw", '2': "nnwwnnnnw", '3': "wnwwnnnnn", '4': "nnnwwnnnw",
+	'5': "wnnwwnnnn", '6': "nnwwwnnnn", '7': "nnnwnnwnw", '8': "wnnwnnwnn", '9': "nnwwnnwnn",
+	'A': "wnnnnwnnw", 'B': "nnwnnwnnw", 'C': "wnwnnwnnn", 'D': "nnnnwwnnw", 'E': "wnnnwwnnn",
+	'F': "nnwnwwnnn", 'G': "nnnnnwwnw", 'H': "wnnnnwwnn", 'I': "nnwnnwwnn", 'J': "nnnnwwwnn",
+	'K': "wnnnnnnww", 'L': "nnwnnnnww", 'M': "wnwnnnnwn", 'N': "nnnnwnnww", 'O': "wnnnwnnwn",
+	'P': "nnwnwnnwn", 'Q': "nnnnnnwww", 'R': "wnnnnnwwn", 'S': "nnwnnnwwn", 'T': "nnnnwnwwn",
+	'U': "wwnnnnnnw", 'V': "nwwnnnnnw", 'W': "wwwnnnnnn", 'X': "nwnnwnnnw", 'Y': "wwnnwnnnn",
+	'Z': "nwwnwnnnn", '-': "nwnnnnwnw", '.': "wwnnnnwnn", ' ': "nwwnnnwnn", '*': "nwnnwnwnn",
+}
+
+// drawCode39 gambar barcode (start/stop '*' ditambah otomatis), balikin tingginya
+func drawCode39(dst *image.RGBA, value string, x, y, narrow, height int) int {
+	wide := narrow * 3
+	for _, c := range "*" + value + "*" {
+		for i, e := range code39[c] {
+			width := narrow
+			if e == 'w' {
+				width = wide
+			}
+			if i%2 == 0 {
+				fillRect(dst, x, y, x+width, y+height, color.Black)
+			}
+			x += width
+		}
+		x += narrow // Jarak antar karakter
+	}
+	return height
+}
+
+func fillRect(dst *image.RGBA, x0, y0, x1, y1 int, c color.Color) {
+	xdraw.Draw(dst, image.Rect(x0, y0, x1, y1), image.NewUniform(c), image.Point{}, xdraw.Src)
+}
+
+func writeJPEG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := jpeg.Encode(f, img, &jpeg.Options{Quality: 90}); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
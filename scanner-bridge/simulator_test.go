@@ -0,0 +1,142 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// simTest nyiapin profile simulator per skenario dan folder session sementara
+func simTest(t *testing.T, scenarios map[string]string) *httptest.Server {
+	t.Helper()
+	dir := t.TempDir()
+	oldSim, oldRoot, oldSessions := config.Simulator, sessions.root, sessions.sessions
+	config.Simulator = nil
+	sessions.root = filepath.Join(dir, "sessions")
+	sessions.sessions = make(map[string]*ScanSession)
+	t.Cleanup(func() { config.Simulator, sessions.root, sessions.sessions = oldSim, oldRoot, oldSessions })
+
+	for name, scenario := range scenarios {
+		path := filepath.Join(dir, strings.ReplaceAll(name, " ", "_")+".json")
+		if err := os.WriteFile(path, []byte(scenario), 0644); err != nil {
+			t.Fatal(err)
+		}
+		config.Simulator = append(config.Simulator, SimulatorProfile{Name: name, Scenario: path})
+	}
+	srv := httptest.NewServer(http.HandlerFunc(scanHandler))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func simScan(t *testing.T, srv *httptest.Server, query url.Values) (*http.Response, Response) {
+	t.Helper()
+	resp, err := http.Get(srv.URL + "/scan?" + query.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body Response
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("response bukan JSON valid: %v", err)
+	}
+	return resp, body
+}
+
+// decodePage balikin byte JPEG dari data URI
+func decodePage(t *testing.T, uri string) []byte {
+	t.Helper()
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(uri, "data:image/jpeg;base64,"))
+	if err != nil {
+		t.Fatalf("data URI rusak: %v", err)
+	}
+	return data
+}
+
+func TestSimulatorScanSession(t *testing.T) {
+	srv := simTest(t, map[string]string{
+		"Sim Duplex": `{"sheets": 2, "duplex": true, "blank_backs": true, "barcode": "BAPP-{sheet}", "dpi": 100}`,
+		"Sim Miring": `{"sheets": 1, "skew_degrees": 4, "dpi": 100}`,
+	})
+
+	resp, body := simScan(t, srv, url.Values{"profile": {"Sim Duplex"}})
+	if resp.StatusCode != http.StatusOK || !body.Success {
+		t.Fatalf("status %d: %s", resp.StatusCode, body.Message)
+	}
+	// Response ditulis per halaman (gak ada Content-Length, chunked)
+	if resp.ContentLength != -1 {
+		t.Errorf("response gak di-stream, Content-Length %d", resp.ContentLength)
+	}
+	if len(body.Data) != 2 {
+		t.Fatalf("dapet %d sheet, harusnya 2", len(body.Data))
+	}
+	for i, pair := range body.Data {
+		if pair.Front == "" || pair.Back == "" {
+			t.Fatalf("sheet %d gak lengkap depan/belakang", i+1)
+		}
+		front := decodePage(t, pair.Front)
+		sum := sha256.Sum256(front)
+		if pair.FrontMeta == nil || pair.FrontMeta.SHA256 != hex.EncodeToString(sum[:]) {
+			t.Fatalf("sheet %d: hash metadata gak cocok dengan gambar", i+1)
+		}
+		if pair.FrontMeta.DPI != 100 {
+			t.Errorf("sheet %d: DPI %d, harusnya 100", i+1, pair.FrontMeta.DPI)
+		}
+		if pair.FrontQuality == nil || pair.FrontQuality.Blank {
+			t.Errorf("sheet %d: depan harusnya ada isinya, quality %+v", i+1, pair.FrontQuality)
+		}
+		if pair.BackQuality == nil || !pair.BackQuality.Blank {
+			t.Errorf("sheet %d: belakang harusnya kosong, quality %+v", i+1, pair.BackQuality)
+		}
+	}
+
+	// Append ke session yang sama, halaman miring harus dapet warning
+	resp, appended := simScan(t, srv, url.Values{"profile": {"Sim Miring"}, "session": {body.SessionID}, "mode": {ModeAppend}})
+	if resp.StatusCode != http.StatusOK || appended.SessionID != body.SessionID {
+		t.Fatalf("append status %d, session %q: %s", resp.StatusCode, appended.SessionID, appended.Message)
+	}
+	if len(appended.Data) != 3 {
+		t.Fatalf("session jadi %d sheet, harusnya 3", len(appended.Data))
+	}
+	if appended.Data[0].Front != body.Data[0].Front {
+		t.Error("sheet lama berubah setelah append")
+	}
+	last := appended.Data[2]
+	if last.Back != "" {
+		t.Error("skenario simplex gak boleh punya halaman belakang")
+	}
+	skewed := false
+	for _, w := range last.FrontQuality.Warnings {
+		skewed = skewed || w.Code == "skewed"
+	}
+	if !skewed {
+		t.Errorf("halaman miring gak dapet warning skewed: %+v", last.FrontQuality)
+	}
+}
+
+func TestSimulatorScanErrors(t *testing.T) {
+	srv := simTest(t, map[string]string{
+		"Sim Macet":   `{"sheets": 3, "error": "paper_jam", "error_after_pages": 1}`,
+		"Sim Offline": `{"error": "offline"}`,
+	})
+	for profile, want := range map[string]string{
+		"Sim Macet":   "kertas macet (paper jam) di halaman 2",
+		"Sim Offline": "scanner simulator offline",
+	} {
+		resp, body := simScan(t, srv, url.Values{"profile": {profile}})
+		if resp.StatusCode != http.StatusInternalServerError || body.Success || !strings.Contains(body.Message, want) {
+			t.Errorf("%s: status %d, message %q", profile, resp.StatusCode, body.Message)
+		}
+	}
+	// Halaman yang udah ke-scan sebelum macet gak boleh nyisa jadi session
+	if n := len(sessions.list("")); n != 0 {
+		t.Errorf("ada %d session nyisa setelah scan gagal", n)
+	}
+}
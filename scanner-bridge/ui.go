@@ -0,0 +1,63 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"os/exec"
+	"strings"
+)
+
+// UI lokal: halaman scan minimal yang ikut di-embed ke exe, buat mode darurat
+// kalau frontend Next.js mati atau jaringan putus. Tetap lewat pairing biasa.
+
+//go:embed ui
+var uiFiles embed.FS
+
+const uiPath = "/ui/"
+
+func uiHandler() http.Handler {
+	sub, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		panic(err) // Cuma bisa gagal kalau folder ui gak ke-embed
+	}
+	files := http.StripPrefix(uiPath, http.FileServer(http.FS(sub)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Halaman ini bisa nyuruh scan & kirim dokumen, jangan mau di-iframe
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.Header().Set("Content-Security-Policy", "default-src 'self'; img-src 'self' data:; frame-ancestors 'none'")
+		files.ServeHTTP(w, r)
+	})
+}
+
+// rootHandler: buka http://localhost:5000 langsung ke UI lokal
+func rootHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	http.Redirect(w, r, uiPath, http.StatusFound)
+}
+
+// bridgeOrigin: origin UI lokal (bridge sendiri), selalu diizinkan
+func bridgeOrigin(origin string) bool {
+	origins := []string{"http://localhost" + bridgePort, "http://127.0.0.1" + bridgePort}
+	if config.TLS {
+		origins = append(origins, "https://localhost"+config.TLSPort, "https://127.0.0.1"+config.TLSPort)
+	}
+	for _, o := range origins {
+		if strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// openLocalUI buka UI lokal di browser default, anchor opsional (mis. "logs")
+func openLocalUI(anchor string) error {
+	url := "http://localhost" + bridgePort + uiPath
+	if anchor != "" {
+		url += "#" + anchor
+	}
+	return exec.Command("cmd", "/c", "start", "", url).Start()
+}
@@ -0,0 +1,337 @@
+// UI lokal bridge: pairing, scan, preview, isi data dokumen, kirim / outbox.
+// Semua request ke bridge ini sendiri (same origin), token dari pairing.
+
+const TOKEN_KEY = "owo_bridge_token";
+
+const state = {
+  token: localStorage.getItem(TOKEN_KEY) || "",
+  sessionId: "",
+  sheets: [],
+};
+
+const $ = (id) => document.getElementById(id);
+
+function showStatus(message, isError) {
+  const el = $("status");
+  el.textContent = message;
+  el.className = isError ? "status error" : "status";
+  el.hidden = !message;
+}
+
+async function api(path, options = {}) {
+  const headers = Object.assign({}, options.headers, { "X-Bridge-Token": state.token });
+  const res = await fetch(path, Object.assign({}, options, { headers }));
+  let body = {};
+  try {
+    body = await res.json();
+  } catch (e) {
+    // Response kosong / bukan JSON
+  }
+  if (res.status === 401) {
+    localStorage.removeItem(TOKEN_KEY);
+    state.token = "";
+    showPairing();
+  }
+  if (!res.ok) {
+    throw new Error(body.message || "HTTP " + res.status);
+  }
+  return body;
+}
+
+// ---------- Pairing ----------
+
+function showPairing() {
+  $("pairing").hidden = false;
+  $("app").hidden = true;
+}
+
+async function startPairing() {
+  const res = await fetch("/pair", { method: "POST" });
+  const body = await res.json();
+  if (!res.ok) {
+    showStatus(body.message || "Gagal minta pairing", true);
+    return;
+  }
+  const code = $("pair-code");
+  code.querySelector("strong").textContent = body.code;
+  code.hidden = false;
+  $("pair-start").disabled = true;
+
+  const timer = setInterval(async () => {
+    const r = await fetch("/pair?id=" + encodeURIComponent(body.request_id));
+    const s = await r.json();
+    if (s.status === "approved" && s.token) {
+      clearInterval(timer);
+      state.token = s.token;
+      localStorage.setItem(TOKEN_KEY, s.token);
+      code.hidden = true;
+      $("pair-start").disabled = false;
+      start();
+    } else if (s.status === "expired") {
+      clearInterval(timer);
+      code.hidden = true;
+      $("pair-start").disabled = false;
+      showStatus("Permintaan pairing kadaluarsa, coba lagi", true);
+    }
+  }, 2000);
+}
+
+// ---------- Scan & session ----------
+
+async function loadProfiles() {
+  const body = await api("/profiles");
+  const select = $("profile");
+  select.replaceChildren();
+  for (const name of body.profiles || []) {
+    select.append(new Option(name, name));
+  }
+}
+
+async function loadSessions() {
+  const body = await api("/sessions");
+  const select = $("saved-sessions");
+  select.replaceChildren(new Option("-", ""));
+  for (const s of body.data || []) {
+    const label = `${s.id} (${s.source}, ${s.sheets} lembar${s.warnings ? ", " + s.warnings + " warning" : ""})`;
+    select.append(new Option(label, s.id));
+  }
+}
+
+function setSession(body) {
+  state.sessionId = body.session_id || "";
+  state.sheets = body.data || [];
+  renderSheets();
+}
+
+async function scan(params) {
+  const query = new URLSearchParams(params);
+  query.set("profile", $("profile").value);
+  showStatus("Scanning...");
+  setBusy(true);
+  try {
+    setSession(await api("/scan?" + query.toString()));
+    showStatus(`Scan selesai, ${state.sheets.length} lembar di session`);
+  } catch (e) {
+    showStatus("Scan gagal: " + e.message, true);
+  } finally {
+    setBusy(false);
+  }
+}
+
+async function openSession(id) {
+  if (!id) return;
+  try {
+    setSession(await api("/session?id=" + encodeURIComponent(id)));
+    showStatus("");
+  } catch (e) {
+    showStatus("Gagal buka session: " + e.message, true);
+  }
+}
+
+async function discardSession() {
+  if (!state.sessionId || !confirm("Buang semua hasil scan di session ini?")) return;
+  try {
+    await api("/session?id=" + encodeURIComponent(state.sessionId), { method: "DELETE" });
+  } catch (e) {
+    showStatus("Gagal buang session: " + e.message, true);
+    return;
+  }
+  setSession({});
+  showStatus("Session dibuang");
+  loadSessions().catch(() => {});
+}
+
+function setBusy(busy) {
+  for (const id of ["scan-new", "scan-append", "discard", "submit"]) {
+    $(id).disabled = busy || (id !== "scan-new" && !state.sessionId);
+  }
+}
+
+function pageFigure(src, quality, meta, caption) {
+  const fig = document.createElement("figure");
+  const img = document.createElement("img");
+  img.src = src;
+  img.alt = caption;
+  const cap = document.createElement("figcaption");
+  cap.textContent = caption + (quality && quality.blank ? " (kosong)" : "");
+  fig.append(img, cap);
+  if (meta) {
+    const info = document.createElement("div");
+    info.className = "note";
+    info.textContent = `${meta.width}×${meta.height}${meta.dpi ? ", " + meta.dpi + " dpi" : ""}, sha256 ${meta.sha256.slice(0, 12)}…`;
+    info.title = meta.sha256;
+    fig.append(info);
+  }
+  for (const w of (quality && quality.warnings) || []) {
+    const p = document.createElement("div");
+    p.className = "warning";
+    p.textContent = "⚠ " + w.message;
+    fig.append(p);
+  }
+  return fig;
+}
+
+function renderSheets() {
+  $("session-id").textContent = state.sessionId ? "session " + state.sessionId : "";
+  const container = $("sheets");
+  container.replaceChildren();
+  if (state.sheets.length === 0) {
+    const p = document.createElement("p");
+    p.className = "note";
+    p.textContent = "Belum ada hasil scan.";
+    container.append(p);
+  }
+
+  state.sheets.forEach((pair, i) => {
+    const sheet = document.createElement("div");
+    sheet.className = "sheet";
+    const title = document.createElement("strong");
+    title.textContent = "Lembar " + (i + 1);
+
+    const pages = document.createElement("div");
+    pages.className = "pages";
+    pages.append(pageFigure(pair.front, pair.front_quality, pair.front_meta, "Depan"));
+    if (pair.back) {
+      pages.append(pageFigure(pair.back, pair.back_quality, pair.back_meta, "Belakang"));
+    }
+
+    const rescan = document.createElement("button");
+    rescan.textContent = "Scan Ulang Lembar Ini";
+    rescan.onclick = () => scan({ session: state.sessionId, mode: "replace", sheet: i + 1 });
+
+    sheet.append(title, pages, rescan);
+    container.append(sheet);
+  });
+  setBusy(false);
+}
+
+// ---------- Submit & outbox ----------
+
+async function submitDocument(event) {
+  event.preventDefault();
+  const form = new FormData(event.target);
+  const payload = Object.fromEntries(form.entries());
+  payload.session_id = state.sessionId;
+
+  setBusy(true);
+  try {
+    const body = await api("/submit", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(payload),
+    });
+    showStatus(body.message);
+    event.target.reset();
+    setSession({});
+    loadOutbox().catch(() => {});
+  } catch (e) {
+    showStatus("Gagal kirim: " + e.message, true);
+    setBusy(false);
+  }
+}
+
+async function loadOutbox() {
+  const body = await api("/outbox");
+  const tbody = $("outbox").querySelector("tbody");
+  tbody.replaceChildren();
+  for (const item of body.data || []) {
+    const tr = document.createElement("tr");
+    for (const v of [item.id, item.npsn, item.sn_bapp, item.status, item.attempts, item.last_error || ""]) {
+      const td = document.createElement("td");
+      td.textContent = v;
+      tr.append(td);
+    }
+    const td = document.createElement("td");
+    const retry = document.createElement("button");
+    retry.textContent = "Kirim Ulang";
+    retry.onclick = async () => {
+      try {
+        const r = await api("/outbox?id=" + encodeURIComponent(item.id), { method: "POST" });
+        showStatus(r.message || "Dikirim ulang");
+      } catch (e) {
+        showStatus("Kirim ulang gagal: " + e.message, true);
+      }
+      loadOutbox().catch(() => {});
+    };
+    td.append(retry);
+    tr.append(td);
+    tbody.append(tr);
+  }
+}
+
+// ---------- Log ----------
+
+async function loadLogs() {
+  const query = new URLSearchParams({ lines: 300, level: $("log-level").value });
+  if ($("log-query").value) query.set("q", $("log-query").value);
+  const body = await api("/logs?" + query.toString());
+  const tbody = $("log-table").querySelector("tbody");
+  tbody.replaceChildren();
+  // Yang terbaru di atas
+  for (const entry of (body.data || []).reverse()) {
+    const { time, level, msg, ...rest } = entry;
+    const tr = document.createElement("tr");
+    tr.className = level;
+    const detail = Object.entries(rest).map(([k, v]) => `${k}=${typeof v === "object" ? JSON.stringify(v) : v}`).join(" ");
+    for (const v of [new Date(time).toLocaleString(), level, msg, detail]) {
+      const td = document.createElement("td");
+      td.textContent = v;
+      tr.append(td);
+    }
+    tbody.append(tr);
+  }
+}
+
+async function downloadLogs() {
+  const res = await fetch("/logs?format=zip", { headers: { "X-Bridge-Token": state.token } });
+  if (!res.ok) {
+    showStatus("Gagal download log: HTTP " + res.status, true);
+    return;
+  }
+  const disposition = res.headers.get("Content-Disposition") || "";
+  const match = disposition.match(/filename="([^"]+)"/);
+  const a = document.createElement("a");
+  a.href = URL.createObjectURL(await res.blob());
+  a.download = match ? match[1] : "bridge-log.zip";
+  a.click();
+  URL.revokeObjectURL(a.href);
+}
+
+// ---------- Start ----------
+
+async function start() {
+  if (!state.token) {
+    showPairing();
+    return;
+  }
+  try {
+    await loadProfiles();
+  } catch (e) {
+    if (state.token) showStatus("Gagal baca profile: " + e.message, true);
+    return;
+  }
+  $("pairing").hidden = true;
+  $("app").hidden = false;
+  showStatus("");
+  loadSessions().catch(() => {});
+  loadOutbox().catch(() => {});
+  loadLogs().catch(() => {});
+  if (location.hash) {
+    const target = document.getElementById(location.hash.slice(1));
+    if (target) target.scrollIntoView();
+  }
+}
+
+$("pair-start").onclick = startPairing;
+$("scan-new").onclick = () => scan({});
+$("scan-append").onclick = () => scan({ session: state.sessionId, mode: "append" });
+$("discard").onclick = discardSession;
+$("open-session").onclick = () => openSession($("saved-sessions").value);
+$("refresh-sessions").onclick = () => loadSessions().catch((e) => showStatus(e.message, true));
+$("refresh-outbox").onclick = () => loadOutbox().catch((e) => showStatus(e.message, true));
+$("submit-form").onsubmit = submitDocument;
+$("refresh-logs").onclick = () => loadLogs().catch((e) => showStatus(e.message, true));
+$("log-level").onchange = $("refresh-logs").onclick;
+$("download-logs").onclick = downloadLogs;
+
+start();